@@ -0,0 +1,140 @@
+package cl
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+//////////////// Basic Types ////////////////
+
+type ErrArgumentLayout struct {
+	Type   reflect.Type
+	Reason string
+}
+
+func (e ErrArgumentLayout) Error() string {
+	return fmt.Sprintf("cl: cannot pass %s as a kernel argument: %s", e.Type, e.Reason)
+}
+
+//////////////// Basic Functions ////////////////
+
+// Whether t is a Go array that can stand for an OpenCL C vector type.
+func isVectorType(t reflect.Type) bool {
+	if t.Kind() != reflect.Array || !isScalarKind(t.Elem().Kind()) {
+		return false
+	}
+	switch t.Len() {
+	case 2, 3, 4, 8, 16:
+		return true
+	}
+	return false
+}
+
+func isScalarKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int8, reflect.Uint8, reflect.Int16, reflect.Uint16, reflect.Int32, reflect.Uint32,
+		reflect.Int64, reflect.Uint64, reflect.Int, reflect.Uint, reflect.Uintptr, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// Size and alignment of t when laid out following the OpenCL C rules:
+// scalars and vectors are aligned to their size (a 3-component vector has
+// the size of a 4-component one), arrays to their element and structs to
+// their most strictly aligned member, with trailing padding up to that
+// alignment. The vector types (Float4, Int2, ...) are always laid out as
+// vectors; when vector is set any other array of 2, 3, 4, 8 or 16 scalars
+// is too, otherwise such arrays are plain C arrays.
+func argLayout(t reflect.Type, vector bool) (size, align int, err error) {
+	switch {
+	case isScalarKind(t.Kind()):
+		size = int(t.Size())
+		return size, size, nil
+	case t.Kind() == reflect.Array && (vector && isVectorType(t) || vectorTypes[t]):
+		lanes := t.Len()
+		if lanes == 3 {
+			lanes = 4
+		}
+		size = lanes * int(t.Elem().Size())
+		return size, size, nil
+	case t.Kind() == reflect.Array:
+		elemSize, elemAlign, err := argLayout(t.Elem(), false)
+		if err != nil {
+			return 0, 0, err
+		}
+		return elemSize * t.Len(), elemAlign, nil
+	case t.Kind() == reflect.Struct:
+		align = 1
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			fSize, fAlign, err := argLayout(f.Type, false)
+			if err != nil {
+				return 0, 0, err
+			}
+			size = alignUp(size, fAlign) + fSize
+			if fAlign > align {
+				align = fAlign
+			}
+		}
+		return alignUp(size, align), align, nil
+	case t.Kind() == reflect.Bool:
+		return 0, 0, ErrArgumentLayout{Type: t, Reason: "bool has no defined size inside an OpenCL C struct"}
+	}
+	return 0, 0, ErrArgumentLayout{Type: t, Reason: fmt.Sprintf("%s values cannot be passed by value", t.Kind())}
+}
+
+func alignUp(n, align int) int {
+	return (n + align - 1) / align * align
+}
+
+// Copies the value of type t at src into dst following argLayout.
+func encodeArg(dst []byte, t reflect.Type, src unsafe.Pointer, vector bool) {
+	switch {
+	case isScalarKind(t.Kind()):
+		copy(dst, unsafe.Slice((*byte)(src), t.Size()))
+	case t.Kind() == reflect.Array && (vector && isVectorType(t) || vectorTypes[t]):
+		copy(dst, unsafe.Slice((*byte)(src), t.Size()))
+	case t.Kind() == reflect.Array:
+		elem := t.Elem()
+		elemSize, _, _ := argLayout(elem, false)
+		for i := 0; i < t.Len(); i++ {
+			encodeArg(dst[i*elemSize:], elem, unsafe.Add(src, uintptr(i)*elem.Size()), false)
+		}
+	case t.Kind() == reflect.Struct:
+		offset := 0
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			fSize, fAlign, _ := argLayout(f.Type, false)
+			offset = alignUp(offset, fAlign)
+			encodeArg(dst[offset:], f.Type, unsafe.Add(src, f.Offset), false)
+			offset += fSize
+		}
+	}
+}
+
+// Lays out arg in memory the way an OpenCL C kernel expects a by-value
+// argument of the corresponding type.
+func marshalArg(arg interface{}) ([]byte, error) {
+	v := reflect.ValueOf(arg)
+	if !v.IsValid() {
+		return nil, ErrArgumentLayout{Type: reflect.TypeOf(arg), Reason: "nil value"}
+	}
+	t := v.Type()
+	if t.Kind() == reflect.Array && !isVectorType(t) {
+		return nil, ErrArgumentLayout{Type: t, Reason: "arrays can only be passed as 2, 3, 4, 8 or 16 component vectors"}
+	}
+	size, _, err := argLayout(t, true)
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 {
+		return nil, ErrArgumentLayout{Type: t, Reason: "zero sized value"}
+	}
+	tmp := reflect.New(t)
+	tmp.Elem().Set(v)
+	buf := make([]byte, size)
+	encodeArg(buf, t, tmp.UnsafePointer(), true)
+	return buf, nil
+}
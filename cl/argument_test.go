@@ -0,0 +1,87 @@
+package cl
+
+import (
+	"bytes"
+	"testing"
+	"unsafe"
+)
+
+func TestMarshalArgLayout(t *testing.T) {
+	type inner struct {
+		A int8
+		B int64
+	}
+	type params struct {
+		Scale float32
+		Flag  uint8
+		Data  [3]int16
+		In    inner
+	}
+	buf, err := marshalArg(params{Scale: 2, Flag: 7, Data: [3]int16{1, 2, 3}, In: inner{A: -1, B: 9}})
+	if err != nil {
+		t.Fatalf("marshalArg failed: %+v", err)
+	}
+	// Scale @0, Flag @4, Data @6..12, In aligned to 8 @16 (A @16, B @24), total 32
+	if len(buf) != 32 {
+		t.Fatalf("expected 32 bytes, got %d", len(buf))
+	}
+	scale := float32(2)
+	if !bytes.Equal(buf[0:4], unsafe.Slice((*byte)(unsafe.Pointer(&scale)), 4)) || buf[4] != 7 {
+		t.Errorf("unexpected scalar layout: %v", buf[:8])
+	}
+	if *(*int16)(unsafe.Pointer(&buf[10])) != 3 || int8(buf[16]) != -1 || *(*int64)(unsafe.Pointer(&buf[24])) != 9 {
+		t.Errorf("unexpected nested layout: %v", buf)
+	}
+
+	vec, err := marshalArg([3]float32{1, 2, 3})
+	if err != nil {
+		t.Fatalf("marshalArg failed for vector: %+v", err)
+	}
+	if len(vec) != 16 {
+		t.Errorf("3-component vector should occupy 16 bytes, got %d", len(vec))
+	}
+
+	if _, err := marshalArg([5]float32{}); err == nil {
+		t.Errorf("expected an error for a 5 element array")
+	}
+	if _, err := marshalArg(struct{ P *int }{}); err == nil {
+		t.Errorf("expected an error for a struct holding a pointer")
+	}
+}
+
+func TestMarshalArgVectorMembers(t *testing.T) {
+	type particle struct {
+		Mass float32
+		Pos  Float3
+		Vel  [3]float32
+	}
+	buf, err := marshalArg(particle{Mass: 1, Pos: Float3{1, 2, 3}, Vel: [3]float32{4, 5, 6}})
+	if err != nil {
+		t.Fatalf("marshalArg failed: %+v", err)
+	}
+	// Mass @0, Pos aligned to 16 @16..32, Vel as float[3] @32..44, padded to 48
+	if len(buf) != 48 {
+		t.Fatalf("expected 48 bytes, got %d", len(buf))
+	}
+	if *(*float32)(unsafe.Pointer(&buf[24])) != 3 || *(*float32)(unsafe.Pointer(&buf[40])) != 6 {
+		t.Errorf("unexpected vector member layout: %v", buf)
+	}
+}
+
+func TestSetArgNil(t *testing.T) {
+	k := &Kernel{}
+	cases := []struct {
+		arg  interface{}
+		want error
+	}{
+		{(*Sampler)(nil), ErrInvalidSampler},
+		{(*MemObject)(nil), ErrInvalidMemObject},
+		{(*Buffer[float32])(nil), ErrInvalidMemObject},
+		{&Buffer[float32]{}, ErrInvalidMemObject},
+	}
+	for _, c := range cases {
+		if err := k.SetArg(0, c.arg); err != c.want {
+			t.Errorf("SetArg(%T nil): got %v, expected %v", c.arg, err, c.want)
+		}
+	}
+}
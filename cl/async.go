@@ -0,0 +1,245 @@
+package cl
+
+/*
+#include <stdint.h>
+#include "./opencl.h"
+extern void go_async_build_notify(cl_program program, uintptr_t id);
+static void CL_CALLBACK c_async_build_notify(cl_program program, void *user_data) {
+        go_async_build_notify(program, (uintptr_t) user_data);
+}
+
+static cl_int CLBuildProgramAsync(cl_program program, cl_uint num_devices, const cl_device_id *devices,
+                                  const char *options, uintptr_t id) {
+        return clBuildProgram(program, num_devices, devices, options, c_async_build_notify, (void *) id);
+}
+
+static cl_int CLCompileProgramAsync(cl_program program, cl_uint num_devices, const cl_device_id *devices,
+                                    const char *options, cl_uint num_headers, const cl_program *headers,
+                                    const char **header_names, uintptr_t id) {
+        return clCompileProgram(program, num_devices, devices, options, num_headers, headers, header_names,
+                                c_async_build_notify, (void *) id);
+}
+
+static cl_program CLLinkProgramAsync(cl_context context, cl_uint num_devices, const cl_device_id *devices,
+                                     const char *options, cl_uint num_programs, const cl_program *programs,
+                                     uintptr_t id, cl_int *err) {
+        return clLinkProgram(context, num_devices, devices, options, num_programs, programs,
+                             c_async_build_notify, (void *) id, err);
+}
+*/
+import "C"
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+//////////////// Abstract Types ////////////////
+
+// Outcome of an asynchronous build, compile or link. Result holds the status
+// and log of every device once the driver has finished, including warnings
+// of successful builds. Err is the BuildError of a failed build, or the error
+// of the context.Context if the caller stopped waiting.
+type BuildOutcome struct {
+	Program *Program
+	Result  *BuildResult
+	Err     error
+}
+
+//////////////// Supporting Types ////////////////
+
+// Builds waiting for their notify callback, keyed by the id handed to the
+// driver as user data.
+var asyncBuilds = struct {
+	sync.Mutex
+	next    uintptr
+	pending map[uintptr]chan C.cl_program
+}{pending: map[uintptr]chan C.cl_program{}}
+
+//////////////// Basic Functions ////////////////
+
+//export go_async_build_notify
+func go_async_build_notify(program C.cl_program, id C.uintptr_t) {
+	asyncBuilds.Lock()
+	ch := asyncBuilds.pending[uintptr(id)]
+	delete(asyncBuilds.pending, uintptr(id))
+	asyncBuilds.Unlock()
+	if ch != nil {
+		ch <- program
+	}
+}
+
+func registerAsyncBuild() (uintptr, chan C.cl_program) {
+	ch := make(chan C.cl_program, 1)
+	asyncBuilds.Lock()
+	defer asyncBuilds.Unlock()
+	asyncBuilds.next++
+	asyncBuilds.pending[asyncBuilds.next] = ch
+	return asyncBuilds.next, ch
+}
+
+func unregisterAsyncBuild(id uintptr) {
+	asyncBuilds.Lock()
+	delete(asyncBuilds.pending, id)
+	asyncBuilds.Unlock()
+}
+
+// Delivers the outcome of a build once the driver signals done, or the error
+// of goCtx if it is cancelled first. A program created by the build (a link)
+// is released when the caller has stopped waiting for it.
+func awaitBuild(goCtx context.Context, done chan C.cl_program, p *Program, devices []*Device, owned bool) <-chan BuildOutcome {
+	out := make(chan BuildOutcome, 1)
+	go func() {
+		select {
+		case <-done:
+		case <-goCtx.Done():
+			out <- BuildOutcome{Err: goCtx.Err()}
+			if owned {
+				<-done
+				p.Release()
+			}
+			return
+		}
+		result, err := p.BuildLogs(devices)
+		if err == nil && result.Failed() {
+			err = p.buildError(devices)
+		}
+		if err != nil && owned {
+			p.Release()
+			p = nil
+		}
+		out <- BuildOutcome{Program: p, Result: result, Err: err}
+	}()
+	return out
+}
+
+func failedBuild(err error) <-chan BuildOutcome {
+	out := make(chan BuildOutcome, 1)
+	out <- BuildOutcome{Err: err}
+	return out
+}
+
+// Delivers the outcome of a build the driver failed before returning, as
+// drivers that build synchronously may do without calling back. A build,
+// compile or link failure carries the logs returned by logs, as it would
+// coming from awaitBuild; other errors and failures whose logs cannot be
+// read are delivered as is.
+func failedSyncBuild(err error, logs func() (*BuildResult, error)) <-chan BuildOutcome {
+	if !errors.Is(err, ErrBuildProgramFailure) && !errors.Is(err, ErrCompileProgramFailure) && !errors.Is(err, ErrLinkProgramFailure) {
+		return failedBuild(err)
+	}
+	result, logErr := logs()
+	if logErr != nil {
+		return failedBuild(err)
+	}
+	out := make(chan BuildOutcome, 1)
+	out <- BuildOutcome{Result: result, Err: result.buildError()}
+	return out
+}
+
+//////////////// Abstract Functions ////////////////
+
+// Starts building the program like BuildProgram but returns immediately. The
+// outcome is sent on the returned channel when the driver reports that the
+// build has finished, or as soon as goCtx is cancelled. A cancelled build
+// keeps running in the driver; the program must not be released before it
+// completes.
+func (p *Program) BuildProgramAsync(goCtx context.Context, devices []*Device, options string) <-chan BuildOutcome {
+	cOptions := C.CString(defaultBuildOptions(options))
+	defer C.free(unsafe.Pointer(cOptions))
+	var deviceListPtr *C.cl_device_id
+	if len(devices) > 0 {
+		deviceList := buildDeviceIdList(devices)
+		deviceListPtr = &deviceList[0]
+	}
+
+	id, done := registerAsyncBuild()
+	if err := C.CLBuildProgramAsync(p.clProgram, C.cl_uint(len(devices)), deviceListPtr, cOptions, C.uintptr_t(id)); err != C.CL_SUCCESS {
+		unregisterAsyncBuild(id)
+		return failedSyncBuild(toError(err), func() (*BuildResult, error) { return p.BuildLogs(devices) })
+	}
+	return awaitBuild(goCtx, done, p, devices, false)
+}
+
+// Starts compiling the program like CompileProgram but returns immediately.
+// See BuildProgramAsync.
+func (p *Program) CompileProgramAsync(goCtx context.Context, devices []*Device, options string, program_headers []*ProgramHeaders) <-chan BuildOutcome {
+	var cOptions *C.char
+	if options != "" {
+		cOptions = C.CString(options)
+		defer C.free(unsafe.Pointer(cOptions))
+	}
+	var deviceListPtr *C.cl_device_id
+	if len(devices) > 0 {
+		deviceList := buildDeviceIdList(devices)
+		deviceListPtr = &deviceList[0]
+	}
+	var cHeadersPtr *C.cl_program
+	var cHeader_namesPtr **C.char
+	if len(program_headers) > 0 {
+		cHeaders, cHeader_names := buildHeaderList(program_headers)
+		defer freeHeaderNames(cHeader_names)
+		cHeadersPtr = &cHeaders[0]
+		cHeader_namesPtr = &cHeader_names[0]
+	}
+
+	id, done := registerAsyncBuild()
+	err := C.CLCompileProgramAsync(p.clProgram, C.cl_uint(len(devices)), deviceListPtr, cOptions, C.cl_uint(len(program_headers)), cHeadersPtr, cHeader_namesPtr, C.uintptr_t(id))
+	if err != C.CL_SUCCESS {
+		unregisterAsyncBuild(id)
+		return failedSyncBuild(toError(err), func() (*BuildResult, error) { return p.BuildLogs(devices) })
+	}
+	return awaitBuild(goCtx, done, p, devices, false)
+}
+
+// Starts linking programs like LinkProgram but returns immediately. The
+// linked program is delivered in the Program field of the outcome. If goCtx
+// is cancelled first the linked program is released once the driver is done
+// with it. See BuildProgramAsync.
+func (ctx *Context) LinkProgramAsync(goCtx context.Context, programs []*Program, devices []*Device, options string) <-chan BuildOutcome {
+	if len(programs) == 0 {
+		return failedBuild(ErrInvalidValue)
+	}
+	var cOptions *C.char
+	if options != "" {
+		cOptions = C.CString(options)
+		defer C.free(unsafe.Pointer(cOptions))
+	}
+	var deviceListPtr *C.cl_device_id
+	if len(devices) > 0 {
+		deviceList := buildDeviceIdList(devices)
+		deviceListPtr = &deviceList[0]
+	}
+	programList := make([]C.cl_program, len(programs))
+	for idx, prog := range programs {
+		programList[idx] = prog.clProgram
+	}
+
+	id, done := registerAsyncBuild()
+	var err C.cl_int
+	clProgram := C.CLLinkProgramAsync(ctx.clContext, C.cl_uint(len(devices)), deviceListPtr, cOptions, C.cl_uint(len(programs)), &programList[0], C.uintptr_t(id), &err)
+	if err != C.CL_SUCCESS || clProgram == nil {
+		unregisterAsyncBuild(id)
+		if err == C.CL_SUCCESS {
+			if clProgram != nil {
+				C.clReleaseProgram(clProgram)
+			}
+			return failedBuild(ErrUnknown)
+		}
+		// A failed link may still return the program holding its logs,
+		// which have to be read before it is released
+		var result *BuildResult
+		logErr := ErrInvalidProgram
+		if clProgram != nil {
+			result, logErr = (&Program{clProgram: clProgram, devices: devices}).BuildLogs(devices)
+			C.clReleaseProgram(clProgram)
+		}
+		return failedSyncBuild(toError(err), func() (*BuildResult, error) { return result, logErr })
+	}
+	program := &Program{clProgram: clProgram, devices: devices}
+	runtime.SetFinalizer(program, releaseProgram)
+	return awaitBuild(goCtx, done, program, devices, true)
+}
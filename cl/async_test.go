@@ -0,0 +1,35 @@
+package cl
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFailedSyncBuild(t *testing.T) {
+	logs := &BuildResult{Devices: []DeviceBuildLog{
+		{Status: BuildStatusError},
+		{Status: BuildStatusError, Log: "kernel.cl:3:5: error: use of undeclared identifier 'x'"},
+	}}
+	for _, failure := range []error{ErrBuildProgramFailure, ErrCompileProgramFailure, ErrLinkProgramFailure} {
+		outcome := <-failedSyncBuild(failure, func() (*BuildResult, error) { return logs, nil })
+		var buildErr BuildError
+		if !errors.As(outcome.Err, &buildErr) {
+			t.Fatalf("%v: got %v, expected a BuildError", failure, outcome.Err)
+		}
+		if buildErr.Message != logs.Devices[1].Log || buildErr.Result != logs || outcome.Result != logs {
+			t.Errorf("%v: logs lost: %+v", failure, outcome)
+		}
+	}
+
+	outcome := <-failedSyncBuild(ErrBuildProgramFailure, func() (*BuildResult, error) { return nil, ErrInvalidProgram })
+	if outcome.Err != ErrBuildProgramFailure || outcome.Result != nil {
+		t.Errorf("unreadable logs: got %+v, expected the build failure", outcome)
+	}
+	outcome = <-failedSyncBuild(ErrInvalidBuildOptions, func() (*BuildResult, error) {
+		t.Error("logs read for an error that is not a build failure")
+		return nil, nil
+	})
+	if outcome.Err != ErrInvalidBuildOptions {
+		t.Errorf("got %v, expected ErrInvalidBuildOptions", outcome.Err)
+	}
+}
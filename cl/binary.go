@@ -0,0 +1,204 @@
+package cl
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+//////////////// Constants ////////////////
+
+const binaryBundleMagic = "GOCLPKG1"
+
+// Upper bounds guarding ReadBinaries against corrupt length fields.
+const (
+	maxBundleEntries = 1 << 10
+	maxBundleString  = 1 << 16
+	maxBundleBinary  = 1 << 31
+)
+
+var (
+	ErrInvalidBinaryBundle = errors.New("cl: invalid program binary bundle")
+	ErrNoMatchingBinary    = errors.New("cl: no binary matches the device")
+)
+
+//////////////// Abstract Types ////////////////
+
+// The binary of a program for one device, labelled with the device and
+// driver that produced it. Binaries are only portable between identical
+// devices running the same driver.
+type DeviceBinary struct {
+	DeviceName      string
+	DeviceVendor    string
+	DeviceVersion   string
+	DriverVersion   string
+	PlatformVersion string
+	Binary          []byte
+}
+
+//////////////// Basic Functions ////////////////
+
+// Labels bin with the identity of device.
+func NewDeviceBinary(device *Device, bin []byte) DeviceBinary {
+	return DeviceBinary{
+		DeviceName:      device.Name(),
+		DeviceVendor:    device.Vendor(),
+		DeviceVersion:   device.Version(),
+		DriverVersion:   device.DriverVersion(),
+		PlatformVersion: device.Platform().Version(),
+		Binary:          bin,
+	}
+}
+
+// Writes binaries to w in a self-describing container: a magic string, the
+// number of entries and, for each entry, the identity strings, the binary
+// and its SHA-256 checksum. All integers are little endian.
+func WriteBinaries(w io.Writer, binaries []DeviceBinary) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(binaryBundleMagic)
+	binary.Write(bw, binary.LittleEndian, uint32(len(binaries)))
+	for _, b := range binaries {
+		for _, s := range []string{b.DeviceName, b.DeviceVendor, b.DeviceVersion, b.DriverVersion, b.PlatformVersion} {
+			binary.Write(bw, binary.LittleEndian, uint32(len(s)))
+			bw.WriteString(s)
+		}
+		binary.Write(bw, binary.LittleEndian, uint64(len(b.Binary)))
+		bw.Write(b.Binary)
+		sum := sha256.Sum256(b.Binary)
+		bw.Write(sum[:])
+	}
+	return bw.Flush()
+}
+
+// Reads n bytes from r into a buffer that grows as they arrive, so that a
+// corrupt length in a truncated bundle cannot force a large allocation.
+func readBundleBytes(r io.Reader, n uint64) ([]byte, error) {
+	var buf bytes.Buffer
+	if copied, err := io.CopyN(&buf, r, int64(n)); err != nil || uint64(copied) != n {
+		return nil, ErrInvalidBinaryBundle
+	}
+	return buf.Bytes(), nil
+}
+
+// Reads binaries written by WriteBinaries.
+func ReadBinaries(r io.Reader) ([]DeviceBinary, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(binaryBundleMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != binaryBundleMagic {
+		return nil, ErrInvalidBinaryBundle
+	}
+	var count uint32
+	if err := binary.Read(br, binary.LittleEndian, &count); err != nil || count > maxBundleEntries {
+		return nil, ErrInvalidBinaryBundle
+	}
+	binaries := make([]DeviceBinary, count)
+	for i := range binaries {
+		b := &binaries[i]
+		for _, s := range []*string{&b.DeviceName, &b.DeviceVendor, &b.DeviceVersion, &b.DriverVersion, &b.PlatformVersion} {
+			var n uint32
+			if err := binary.Read(br, binary.LittleEndian, &n); err != nil || n > maxBundleString {
+				return nil, ErrInvalidBinaryBundle
+			}
+			buf, err := readBundleBytes(br, uint64(n))
+			if err != nil {
+				return nil, err
+			}
+			*s = string(buf)
+		}
+		var n uint64
+		if err := binary.Read(br, binary.LittleEndian, &n); err != nil || n > maxBundleBinary {
+			return nil, ErrInvalidBinaryBundle
+		}
+		var err error
+		if b.Binary, err = readBundleBytes(br, n); err != nil {
+			return nil, err
+		}
+		var sum [sha256.Size]byte
+		if _, err := io.ReadFull(br, sum[:]); err != nil {
+			return nil, ErrInvalidBinaryBundle
+		}
+		if want := sha256.Sum256(b.Binary); !bytes.Equal(sum[:], want[:]) {
+			return nil, fmt.Errorf("%w: checksum mismatch for %s", ErrInvalidBinaryBundle, b.DeviceName)
+		}
+	}
+	return binaries, nil
+}
+
+//////////////// Abstract Functions ////////////////
+
+// Whether the binary was produced for device by its current driver.
+func (b DeviceBinary) Matches(device *Device) bool {
+	return b.DeviceName == device.Name() &&
+		b.DeviceVendor == device.Vendor() &&
+		b.DeviceVersion == device.Version() &&
+		b.DriverVersion == device.DriverVersion() &&
+		b.PlatformVersion == device.Platform().Version()
+}
+
+// Returns the binary of the program for each of its devices, labelled with
+// the device it belongs to. Devices the program was not built for are left
+// out.
+func (p *Program) ExportBinaries() ([]DeviceBinary, error) {
+	devices, err := p.GetDevices()
+	if err != nil {
+		return nil, err
+	}
+	bins, err := p.GetBinaries()
+	if err != nil {
+		return nil, err
+	}
+	var out []DeviceBinary
+	for i, dev := range devices {
+		if i < len(bins) && len(bins[i]) > 0 {
+			out = append(out, NewDeviceBinary(dev, bins[i]))
+		}
+	}
+	return out, nil
+}
+
+// Writes the binaries of the program to w in the format of WriteBinaries.
+func (p *Program) WriteBinaries(w io.Writer) error {
+	bins, err := p.ExportBinaries()
+	if err != nil {
+		return err
+	}
+	return WriteBinaries(w, bins)
+}
+
+// Creates a program for devices (all devices of the context if nil) from
+// the first entry of binaries matching each device. If a device has no
+// matching entry ErrNoMatchingBinary is returned, wrapped with the name of
+// the device. The per-device load status is returned as by
+// CreateProgramWithBinary.
+func (ctx *Context) CreateProgramFromBinaries(binaries []DeviceBinary, devices []*Device) (*Program, []error, error) {
+	if len(devices) == 0 {
+		devices = ctx.devices
+	}
+	bins := make([][]byte, len(devices))
+	for i, dev := range devices {
+		for _, b := range binaries {
+			if b.Matches(dev) {
+				bins[i] = b.Binary
+				break
+			}
+		}
+		if bins[i] == nil {
+			return nil, nil, fmt.Errorf("%w: %s", ErrNoMatchingBinary, dev.Name())
+		}
+	}
+	return ctx.CreateProgramWithBinary(devices, bins)
+}
+
+// Reads binaries written by WriteBinaries from r and creates a program for
+// devices from them. See CreateProgramFromBinaries.
+func (ctx *Context) ReadProgramBinaries(r io.Reader, devices []*Device) (*Program, []error, error) {
+	binaries, err := ReadBinaries(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ctx.CreateProgramFromBinaries(binaries, devices)
+}
@@ -0,0 +1,62 @@
+package cl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestBinaryBundleRoundTrip(t *testing.T) {
+	in := []DeviceBinary{
+		{DeviceName: "GPU", DeviceVendor: "Vendor", DeviceVersion: "OpenCL 1.2", DriverVersion: "1.0", PlatformVersion: "OpenCL 1.2", Binary: []byte{1, 2, 3}},
+		{DeviceName: "CPU", Binary: []byte("binary")},
+	}
+	var buf bytes.Buffer
+	if err := WriteBinaries(&buf, in); err != nil {
+		t.Fatalf("WriteBinaries failed: %+v", err)
+	}
+	data := buf.Bytes()
+	out, err := ReadBinaries(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadBinaries failed: %+v", err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", out, in)
+	}
+
+	// Flip a byte of the last binary
+	corrupt := append([]byte(nil), data...)
+	corrupt[len(corrupt)-40] ^= 0xff
+	if _, err := ReadBinaries(bytes.NewReader(corrupt)); !errors.Is(err, ErrInvalidBinaryBundle) {
+		t.Errorf("expected ErrInvalidBinaryBundle for a corrupt bundle, got %v", err)
+	}
+	if _, err := ReadBinaries(bytes.NewReader(data[:len(data)-1])); !errors.Is(err, ErrInvalidBinaryBundle) {
+		t.Errorf("expected ErrInvalidBinaryBundle for a truncated bundle, got %v", err)
+	}
+}
+
+func TestReadBinariesHugeLength(t *testing.T) {
+	// One entry with empty labels and a binary claiming 2 GiB, then nothing
+	var buf bytes.Buffer
+	buf.WriteString(binaryBundleMagic)
+	binary.Write(&buf, binary.LittleEndian, uint32(1))
+	for i := 0; i < 5; i++ {
+		binary.Write(&buf, binary.LittleEndian, uint32(0))
+	}
+	binary.Write(&buf, binary.LittleEndian, uint64(maxBundleBinary))
+	buf.Write(make([]byte, 100))
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := ReadBinaries(bytes.NewReader(buf.Bytes()))
+	runtime.ReadMemStats(&after)
+	if !errors.Is(err, ErrInvalidBinaryBundle) {
+		t.Errorf("expected ErrInvalidBinaryBundle for a truncated binary, got %v", err)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		t.Errorf("allocated %d bytes for a 100 byte binary", allocated)
+	}
+}
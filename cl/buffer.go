@@ -0,0 +1,178 @@
+package cl
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+//////////////// Basic Types ////////////////
+
+// Scalar is the set of Go types with the same size and layout as an OpenCL C
+// scalar type:
+//
+//	int8/uint8    char/uchar
+//	int16/uint16  short/ushort (uint16 is also used for half)
+//	int32/uint32  int/uint
+//	int64/uint64  long/ulong
+//	float32       float
+//	float64       double
+type Scalar interface {
+	~int8 | ~uint8 | ~int16 | ~uint16 | ~int32 | ~uint32 | ~int64 | ~uint64 | ~float32 | ~float64
+}
+
+// BufferElement is the set of Go types that can be stored in a typed Buffer.
+type BufferElement interface {
+	Scalar | Vector
+}
+
+type ErrBufferRange struct {
+	Offset int
+	Count  int
+	Length int
+}
+
+func (e ErrBufferRange) Error() string {
+	return fmt.Sprintf("cl: buffer range [%d, %d) out of bounds for buffer of %d elements", e.Offset, e.Offset+e.Count, e.Length)
+}
+
+//////////////// Abstract Types ////////////////
+
+// Buffer is a buffer object holding elements of type T. All offsets and
+// counts taken by its methods are in elements rather than bytes.
+type Buffer[T BufferElement] struct {
+	*MemObject
+	length int
+}
+
+//////////////// Basic Functions ////////////////
+func elementSize[T BufferElement]() int {
+	var v T
+	return int(unsafe.Sizeof(v))
+}
+
+func newBuffer[T BufferElement](mo *MemObject, length int) *Buffer[T] {
+	return &Buffer[T]{MemObject: mo, length: length}
+}
+
+// Creates an uninitialised buffer with room for length elements of type T.
+func CreateEmptyTypedBuffer[T BufferElement](ctx *Context, flags MemFlag, length int) (*Buffer[T], error) {
+	if length <= 0 {
+		return nil, ErrInvalidBufferSize
+	}
+	mo, err := ctx.CreateEmptyBuffer(flags, length*elementSize[T]())
+	if err != nil {
+		return nil, err
+	}
+	return newBuffer[T](mo, length), nil
+}
+
+// Creates a buffer holding len(data) elements of type T. The flags decide
+// whether data is copied (MemCopyHostPtr) or used directly (MemUseHostPtr).
+func CreateTypedBuffer[T BufferElement](ctx *Context, flags MemFlag, data []T) (*Buffer[T], error) {
+	if len(data) == 0 {
+		return nil, ErrInvalidBufferSize
+	}
+	mo, err := ctx.CreateBufferUnsafe(flags, len(data)*elementSize[T](), unsafe.Pointer(&data[0]))
+	if err != nil {
+		return nil, err
+	}
+	return newBuffer[T](mo, len(data)), nil
+}
+
+// Wraps an existing buffer object as a typed buffer. The size of the
+// buffer must be a multiple of the element size of T.
+func AsTypedBuffer[T BufferElement](mo *MemObject) (*Buffer[T], error) {
+	size, err := mo.GetSize()
+	if err != nil {
+		return nil, err
+	}
+	if size%elementSize[T]() != 0 {
+		return nil, ErrInvalidBufferSize
+	}
+	return newBuffer[T](mo, size/elementSize[T]()), nil
+}
+
+//////////////// Abstract Functions ////////////////
+
+// Number of elements in the buffer.
+func (b *Buffer[T]) Len() int {
+	return b.length
+}
+
+func (b *Buffer[T]) elementType() reflect.Type {
+	var v T
+	return reflect.TypeOf(v)
+}
+
+// Size in bytes of a single element.
+func (b *Buffer[T]) ElementSize() int {
+	return elementSize[T]()
+}
+
+func (b *Buffer[T]) checkRange(offset, count int) error {
+	if offset < 0 || count <= 0 || count > b.length-offset {
+		return ErrBufferRange{Offset: offset, Count: count, Length: b.length}
+	}
+	return nil
+}
+
+// Enqueues a command to write data into the buffer starting at element offset.
+func (b *Buffer[T]) EnqueueWrite(q *CommandQueue, blocking bool, offset int, data []T, eventWaitList []*Event) (*Event, error) {
+	if err := b.checkRange(offset, len(data)); err != nil {
+		return nil, err
+	}
+	size := elementSize[T]()
+	return q.EnqueueWriteBuffer(b.MemObject, blocking, offset*size, len(data)*size, unsafe.Pointer(&data[0]), eventWaitList)
+}
+
+// Enqueues a command to read len(data) elements starting at element offset into data.
+func (b *Buffer[T]) EnqueueRead(q *CommandQueue, blocking bool, offset int, data []T, eventWaitList []*Event) (*Event, error) {
+	if err := b.checkRange(offset, len(data)); err != nil {
+		return nil, err
+	}
+	size := elementSize[T]()
+	return q.EnqueueReadBuffer(b.MemObject, blocking, offset*size, len(data)*size, unsafe.Pointer(&data[0]), eventWaitList)
+}
+
+// Enqueues a command to copy count elements from this buffer to dst.
+func (b *Buffer[T]) EnqueueCopy(q *CommandQueue, dst *Buffer[T], srcOffset, dstOffset, count int, eventWaitList []*Event) (*Event, error) {
+	if err := b.checkRange(srcOffset, count); err != nil {
+		return nil, err
+	}
+	if err := dst.checkRange(dstOffset, count); err != nil {
+		return nil, err
+	}
+	size := elementSize[T]()
+	return q.EnqueueCopyBuffer(b.MemObject, dst.MemObject, srcOffset*size, dstOffset*size, count*size, eventWaitList)
+}
+
+// Enqueues a command to set count elements starting at element offset to value.
+func (b *Buffer[T]) EnqueueFill(q *CommandQueue, value T, offset, count int, eventWaitList []*Event) (*Event, error) {
+	if err := b.checkRange(offset, count); err != nil {
+		return nil, err
+	}
+	size := elementSize[T]()
+	return q.EnqueueFillBuffer(b.MemObject, unsafe.Pointer(&value), size, offset*size, count*size, eventWaitList)
+}
+
+// Enqueues a command to map count elements starting at element offset into
+// the host address space. The returned slice aliases the mapped region and
+// must not be used after EnqueueUnmap. For non-blocking maps the slice is only
+// valid once the returned event has completed.
+func (b *Buffer[T]) EnqueueMap(q *CommandQueue, blocking bool, flags MapFlag, offset, count int, eventWaitList []*Event) ([]T, *MappedMemObject, *Event, error) {
+	if err := b.checkRange(offset, count); err != nil {
+		return nil, nil, nil, err
+	}
+	size := elementSize[T]()
+	mapped, event, err := q.EnqueueMapBuffer(b.MemObject, blocking, flags, offset*size, count*size, eventWaitList)
+	if err != nil {
+		return nil, mapped, event, err
+	}
+	return unsafe.Slice((*T)(mapped.Ptr()), count), mapped, event, nil
+}
+
+// Enqueues a command to unmap a region previously mapped with EnqueueMap.
+func (b *Buffer[T]) EnqueueUnmap(q *CommandQueue, mapped *MappedMemObject, eventWaitList []*Event) (*Event, error) {
+	return q.EnqueueUnmapMemObject(b.MemObject, mapped, eventWaitList)
+}
@@ -0,0 +1,65 @@
+package cl
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestBufferCheckRange(t *testing.T) {
+	b := &Buffer[float32]{length: 10}
+	cases := []struct {
+		offset, count int
+		ok            bool
+	}{
+		{0, 10, true},
+		{9, 1, true},
+		{3, 4, true},
+		{-1, 2, false},
+		{0, 0, false},
+		{0, -1, false},
+		{0, 11, false},
+		{8, 3, false},
+		{10, 1, false},
+		{1, math.MaxInt, false},
+		{math.MaxInt, 1, false},
+	}
+	for _, c := range cases {
+		err := b.checkRange(c.offset, c.count)
+		if (err == nil) != c.ok {
+			t.Errorf("checkRange(%d, %d): got %v", c.offset, c.count, err)
+		}
+		var rangeErr ErrBufferRange
+		if err != nil && (!errors.As(err, &rangeErr) || rangeErr != (ErrBufferRange{Offset: c.offset, Count: c.count, Length: 10})) {
+			t.Errorf("checkRange(%d, %d): unexpected error %#v", c.offset, c.count, err)
+		}
+	}
+}
+
+func TestErrBufferRangeMessage(t *testing.T) {
+	err := ErrBufferRange{Offset: 8, Count: 4, Length: 10}
+	if got, want := err.Error(), "cl: buffer range [8, 12) out of bounds for buffer of 10 elements"; got != want {
+		t.Errorf("got %q, expected %q", got, want)
+	}
+}
+
+func TestBufferElementSize(t *testing.T) {
+	cases := []struct {
+		name       string
+		size, want int
+	}{
+		{"int8", (&Buffer[int8]{}).ElementSize(), 1},
+		{"uint16", (&Buffer[uint16]{}).ElementSize(), 2},
+		{"float32", (&Buffer[float32]{}).ElementSize(), 4},
+		{"float64", (&Buffer[float64]{}).ElementSize(), 8},
+		{"Half2", (&Buffer[Half2]{}).ElementSize(), 4},
+		{"Char16", (&Buffer[Char16]{}).ElementSize(), 16},
+		{"Float3", (&Buffer[Float3]{}).ElementSize(), 16}, // padded like a float4
+		{"Float4", (&Buffer[Float4]{}).ElementSize(), 16},
+	}
+	for _, c := range cases {
+		if c.size != c.want {
+			t.Errorf("%s: element size %d, expected %d", c.name, c.size, c.want)
+		}
+	}
+}
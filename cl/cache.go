@@ -0,0 +1,216 @@
+package cl
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"os"
+	"path/filepath"
+)
+
+//////////////// Constants ////////////////
+
+const binaryCacheMagic = "GOCLBIN1"
+
+//////////////// Abstract Types ////////////////
+
+// BinaryCache keeps program binaries on disk so that later processes can
+// skip compiling from source. Entries are keyed by the sources, the build
+// options and the name, driver version and platform version of the device,
+// so a driver update or an edited kernel simply results in a cache miss.
+type BinaryCache struct {
+	dir string
+}
+
+//////////////// Basic Functions ////////////////
+
+// Opens (creating it if needed) a binary cache in dir. An empty dir selects
+// a go2opencl directory below os.UserCacheDir.
+func NewBinaryCache(dir string) (*BinaryCache, error) {
+	if dir == "" {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(base, "go2opencl")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &BinaryCache{dir: dir}, nil
+}
+
+// Writes s to h prefixed with its length, so that the key of a sequence of
+// strings cannot collide with that of the same text split differently.
+func hashString(h hash.Hash, s string) {
+	var n [8]byte
+	binary.LittleEndian.PutUint64(n[:], uint64(len(s)))
+	h.Write(n[:])
+	h.Write([]byte(s))
+}
+
+func binaryCacheKey(sources []string, options string, device *Device) string {
+	return binaryCacheLabelKey(sources, options, device.Name(), device.DriverVersion(), device.Platform().Version())
+}
+
+// The cache key for sources and options built on the device identified by
+// labels.
+func binaryCacheLabelKey(sources []string, options string, labels ...string) string {
+	h := sha256.New()
+	hashString(h, binaryCacheMagic)
+	for _, src := range sources {
+		hashString(h, src)
+	}
+	hashString(h, options)
+	for _, label := range labels {
+		hashString(h, label)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+//////////////// Abstract Functions ////////////////
+
+// Directory holding the cache entries.
+func (c *BinaryCache) Dir() string {
+	return c.dir
+}
+
+func (c *BinaryCache) path(key string) string {
+	return filepath.Join(c.dir, key+".bin")
+}
+
+// Returns the cached binary for key, or nil if there is none. Entries that
+// fail their checksum are removed.
+func (c *BinaryCache) load(key string) []byte {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil
+	}
+	headerLen := len(binaryCacheMagic) + sha256.Size
+	if len(data) <= headerLen || string(data[:len(binaryCacheMagic)]) != binaryCacheMagic {
+		os.Remove(c.path(key))
+		return nil
+	}
+	bin := data[headerLen:]
+	sum := sha256.Sum256(bin)
+	if !bytes.Equal(sum[:], data[len(binaryCacheMagic):headerLen]) {
+		os.Remove(c.path(key))
+		return nil
+	}
+	return bin
+}
+
+func (c *BinaryCache) store(key string, bin []byte) error {
+	sum := sha256.Sum256(bin)
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(append(append([]byte(binaryCacheMagic), sum[:]...), bin...))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(key))
+}
+
+// Removes every entry from the cache.
+func (c *BinaryCache) Clear() error {
+	entries, err := filepath.Glob(filepath.Join(c.dir, "*.bin"))
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := os.Remove(e); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns a program built for devices (all devices of the context if nil)
+// from sources with the given options. When every device has a cached binary
+// the program is created with CreateProgramWithBinary; if any binary is
+// missing, corrupt or rejected by the driver the program is rebuilt from
+// source and the cache refreshed. Failing to write the cache is not an error.
+func (c *BinaryCache) BuildProgram(ctx *Context, sources []string, devices []*Device, options string) (*Program, error) {
+	if len(devices) == 0 {
+		devices = ctx.devices
+	}
+	if len(devices) == 0 {
+		// Contexts not created from a device list, e.g. by type
+		var err error
+		if devices, err = ctx.GetDevices(); err != nil {
+			return nil, err
+		}
+	}
+	keys := make([]string, len(devices))
+	bins := make([][]byte, len(devices))
+	hit := len(devices) > 0
+	for i, dev := range devices {
+		keys[i] = binaryCacheKey(sources, options, dev)
+		bins[i] = c.load(keys[i])
+		hit = hit && bins[i] != nil
+	}
+	if hit {
+		if program, err := c.buildFromBinaries(ctx, devices, bins, keys, options); err == nil {
+			return program, nil
+		}
+	}
+
+	program, err := ctx.CreateProgramWithSource(sources)
+	if err != nil {
+		return nil, err
+	}
+	if err := program.BuildProgram(devices, options); err != nil {
+		program.Release()
+		return nil, err
+	}
+	c.storeProgram(program, devices, keys)
+	return program, nil
+}
+
+func (c *BinaryCache) buildFromBinaries(ctx *Context, devices []*Device, bins [][]byte, keys []string, options string) (*Program, error) {
+	program, statuses, err := ctx.CreateProgramWithBinary(devices, bins)
+	for i, status := range statuses {
+		if status != nil {
+			os.Remove(c.path(keys[i]))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := program.BuildProgram(devices, options); err != nil {
+		program.Release()
+		for _, key := range keys {
+			os.Remove(c.path(key))
+		}
+		return nil, err
+	}
+	return program, nil
+}
+
+// Writes the binary of each of devices to the cache.
+func (c *BinaryCache) storeProgram(program *Program, devices []*Device, keys []string) {
+	progDevices, err := program.GetDevices()
+	if err != nil {
+		return
+	}
+	bins, err := program.GetBinaries()
+	if err != nil {
+		return
+	}
+	for i, dev := range devices {
+		for j, pd := range progDevices {
+			if pd.id == dev.id && j < len(bins) && len(bins[j]) > 0 {
+				c.store(keys[i], bins[j])
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package cl
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBinaryCacheStoreLoad(t *testing.T) {
+	c, err := NewBinaryCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin := []byte("device binary")
+	if err := c.store("k", bin); err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+	if got := c.load("k"); !bytes.Equal(got, bin) {
+		t.Errorf("load: got %q, expected %q", got, bin)
+	}
+	if got := c.load("missing"); got != nil {
+		t.Errorf("load of a missing entry: got %q", got)
+	}
+	if tmp, _ := filepath.Glob(filepath.Join(c.Dir(), "*.tmp")); len(tmp) != 0 {
+		t.Errorf("temporary files left behind: %v", tmp)
+	}
+}
+
+func TestBinaryCacheCorruptEntries(t *testing.T) {
+	c, err := NewBinaryCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.store("k", []byte("device binary")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(c.path("k"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	flipped := append([]byte(nil), data...)
+	flipped[len(flipped)-1] ^= 0xff
+	cases := map[string][]byte{
+		"checksum mismatch": flipped,
+		"bad magic":         append([]byte("NOTMAGIC"), data[len(binaryCacheMagic):]...),
+		"truncated":         data[:len(binaryCacheMagic)+10],
+		"empty binary":      data[:len(data)-len("device binary")],
+	}
+	for name, entry := range cases {
+		if err := os.WriteFile(c.path("k"), entry, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := c.load("k"); got != nil {
+			t.Errorf("%s: load returned %q", name, got)
+		}
+		if _, err := os.Stat(c.path("k")); !os.IsNotExist(err) {
+			t.Errorf("%s: entry not removed", name)
+		}
+	}
+}
+
+func TestBinaryCacheClear(t *testing.T) {
+	c, err := NewBinaryCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := filepath.Join(c.Dir(), "notes.txt")
+	if err := os.WriteFile(other, []byte("kept"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b"} {
+		if err := c.store(key, []byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Clear(); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if c.load("a") != nil || c.load("b") != nil {
+		t.Errorf("entries left after Clear")
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("Clear removed a file that is not an entry: %v", err)
+	}
+}
+
+func TestBinaryCacheKey(t *testing.T) {
+	key := binaryCacheLabelKey([]string{"ab", "c"}, "", "GPU", "1.0", "OpenCL 3.0")
+	if key != binaryCacheLabelKey([]string{"ab", "c"}, "", "GPU", "1.0", "OpenCL 3.0") {
+		t.Fatalf("key not stable")
+	}
+	others := map[string]string{
+		"sources split differently":    binaryCacheLabelKey([]string{"a", "bc"}, "", "GPU", "1.0", "OpenCL 3.0"),
+		"source moved into options":    binaryCacheLabelKey([]string{"ab"}, "c", "GPU", "1.0", "OpenCL 3.0"),
+		"options moved into the label": binaryCacheLabelKey([]string{"ab", "c"}, "G", "PU", "1.0", "OpenCL 3.0"),
+		"other driver":                 binaryCacheLabelKey([]string{"ab", "c"}, "", "GPU", "1.1", "OpenCL 3.0"),
+	}
+	for name, other := range others {
+		if other == key {
+			t.Errorf("%s: same key", name)
+		}
+	}
+}
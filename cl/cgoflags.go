@@ -4,6 +4,7 @@ package cl
 
 //#cgo darwin LDFLAGS: -framework OpenCL
 //#cgo !darwin LDFLAGS: -lOpenCL
+//#cgo linux LDFLAGS: -ldl
 //
 ////default location:
 //#cgo LDFLAGS:-L/usr/lib/x86_64-linux-gnu/
@@ -55,6 +55,7 @@ const (
 type Context struct {
 	clContext C.cl_context
 	devices   []*Device
+	registry  *ProgramRegistry
 }
 
 ////////////////// Golang Types ////////////////
@@ -78,6 +79,9 @@ func go_ctx_notify(errinfo *C.char, private_info unsafe.Pointer, cb C.int, user_
 }
 
 func releaseContext(c *Context) {
+	if r := c.loadRegistry(); r != nil {
+		r.close()
+	}
 	if c.clContext != nil {
 		C.clReleaseContext(c.clContext)
 		c.clContext = nil
@@ -173,21 +177,27 @@ func (ctx *Context) GetReferenceCount() (int, error) {
 }
 
 func (ctx *Context) GetDevices() ([]*Device, error) {
-        if ctx.clContext != nil {
-		var tmpCount C.cl_device_id
-        	var outDevices []C.cl_device_id
-		var devCount C.size_t
-        	err := C.clGetContextInfo(ctx.clContext, C.cl_context_info(ContextDevices), C.size_t(unsafe.Sizeof(tmpCount)), unsafe.Pointer(&outDevices), &devCount)
-		if int(devCount) != 0 {
-			devPtr := make([]*Device, int(devCount))
-			for i := range devPtr {
-				devPtr[i].id = outDevices[i]
-			}
-			return devPtr, toError(err)
-		}
-        	return nil, toError(err)
+        if ctx.clContext == nil {
+                return nil, toError(C.CL_INVALID_CONTEXT)
+        }
+        var size C.size_t
+        if err := C.clGetContextInfo(ctx.clContext, C.cl_context_info(ContextDevices), 0, nil, &size); err != C.CL_SUCCESS {
+                return nil, toError(err)
+        }
+        var tmpId C.cl_device_id
+        count := int(size / C.size_t(unsafe.Sizeof(tmpId)))
+        if count == 0 {
+                return nil, nil
+        }
+        outDevices := make([]C.cl_device_id, count)
+        if err := C.clGetContextInfo(ctx.clContext, C.cl_context_info(ContextDevices), size, unsafe.Pointer(&outDevices[0]), nil); err != C.CL_SUCCESS {
+                return nil, toError(err)
+        }
+        devPtr := make([]*Device, count)
+        for i := range devPtr {
+                devPtr[i] = &Device{id: outDevices[i]}
         }
-        return nil, toError(C.CL_INVALID_CONTEXT)
+        return devPtr, nil
 }
 
 func (ctx *Context) GetNumberOfDevices() (int, error) {
@@ -3,6 +3,11 @@ package cl
 /*
 #include "./opencl.h"
 
+// Also reported by 1.2 devices with cl_khr_il_program as CL_DEVICE_IL_VERSION_KHR
+#ifndef CL_DEVICE_IL_VERSION
+#define CL_DEVICE_IL_VERSION 0x105B
+#endif
+
 
 static cl_device_partition_property * partitionDeviceEqually(unsigned int n) {
 	cl_device_partition_property *properties = malloc(n * sizeof(cl_device_partition_property));
@@ -75,6 +80,7 @@ static cl_device_partition_property * partitionDeviceByNextPartitionable() {
 import "C"
 
 import (
+	"fmt"
 	"strings"
 	"unsafe"
 )
@@ -156,6 +162,15 @@ type Device struct {
 type CLDevice C.cl_device_id
 
 //////////////// Basic Functions ////////////////
+// Parses the "OpenCL <major>.<minor> <vendor specific>" version strings of
+// platforms and devices.
+func parseOpenCLVersion(version string) (major, minor int) {
+	if _, err := fmt.Sscanf(version, "OpenCL %d.%d", &major, &minor); err != nil {
+		return 0, 0
+	}
+	return major, minor
+}
+
 func buildDeviceIdList(devices []*Device) []C.cl_device_id {
 	deviceIds := make([]C.cl_device_id, len(devices))
 	for i, d := range devices {
@@ -302,6 +317,42 @@ func (d *Device) OpenCLCVersion() string {
 	return str
 }
 
+// Major and minor OpenCL version supported by the device, parsed from
+// Version. Both are zero if the version string is malformed.
+func (d *Device) VersionNumber() (major, minor int) {
+	return parseOpenCLVersion(d.Version())
+}
+
+// Whether the device supports at least OpenCL major.minor.
+func (d *Device) HasVersion(major, minor int) bool {
+	devMajor, devMinor := d.VersionNumber()
+	return devMajor > major || devMajor == major && devMinor >= minor
+}
+
+// Whether name is listed in the extensions of the device.
+func (d *Device) HasExtension(name string) bool {
+	for _, ext := range strings.Fields(d.Extensions()) {
+		if ext == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Space separated list of the intermediate languages, with their versions,
+// accepted by CreateProgramWithIL, e.g. "SPIR-V_1.0 SPIR-V_1.2". Empty when
+// the device supports neither OpenCL 2.1 nor cl_khr_il_program.
+func (d *Device) ILVersion() string {
+	if !d.HasVersion(2, 1) && !d.HasExtension("cl_khr_il_program") {
+		return ""
+	}
+	str, err := d.GetInfoString(C.CL_DEVICE_IL_VERSION, false)
+	if err != nil {
+		return ""
+	}
+	return str
+}
+
 // The default compute device address space size specified as an
 // unsigned integer value in bits. Currently supported values are 32 or 64 bits.
 func (d *Device) AddressBits() int {
@@ -0,0 +1,161 @@
+package cl
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//////////////// Basic Types ////////////////
+
+type DiagnosticSeverity int
+
+const (
+	SeverityNote DiagnosticSeverity = iota
+	SeverityRemark
+	SeverityWarning
+	SeverityError
+	SeverityFatal
+)
+
+var diagnosticSeverityMap = map[DiagnosticSeverity]string{
+	SeverityNote:    "note",
+	SeverityRemark:  "remark",
+	SeverityWarning: "warning",
+	SeverityError:   "error",
+	SeverityFatal:   "fatal error",
+}
+
+func (s DiagnosticSeverity) String() string {
+	name := diagnosticSeverityMap[s]
+	if name == "" {
+		name = "Unknown"
+	}
+	return name
+}
+
+// A single message from the OpenCL C compiler. Line and Column are 1-based
+// and zero when the compiler did not report them.
+type Diagnostic struct {
+	Device   *Device
+	Severity DiagnosticSeverity
+	File     string
+	Line     int
+	Column   int
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	loc := d.File
+	if d.Line > 0 {
+		loc += ":" + strconv.Itoa(d.Line)
+		if d.Column > 0 {
+			loc += ":" + strconv.Itoa(d.Column)
+		}
+	}
+	if loc == "" {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", loc, d.Severity, d.Message)
+}
+
+//////////////// Abstract Types ////////////////
+
+// Build log of a program for one device.
+type DeviceBuildLog struct {
+	Device      *Device
+	Status      BuildStatus
+	Log         string
+	Diagnostics []Diagnostic
+}
+
+// Build logs of a program for every device it was built for.
+type BuildResult struct {
+	Devices []DeviceBuildLog
+}
+
+//////////////// Basic Functions ////////////////
+
+var (
+	// clang based compilers (most ICDs): "file:line:col: error: message"
+	clangDiagnostic = regexp.MustCompile(`^(.*?):(\d+):(?:(\d+):)? *(fatal error|error|warning|note|remark): *(.*)$`)
+	// EDG based compilers (older AMD drivers): "\"file\", line 5: error: message"
+	edgDiagnostic = regexp.MustCompile(`^"(.*)", line (\d+): *(catastrophic error|error|warning|remark)(?: #[0-9A-Za-z-]+)?: *(.*)$`)
+)
+
+func parseSeverity(s string) DiagnosticSeverity {
+	switch s {
+	case "fatal error", "catastrophic error":
+		return SeverityFatal
+	case "error":
+		return SeverityError
+	case "warning":
+		return SeverityWarning
+	case "remark":
+		return SeverityRemark
+	}
+	return SeverityNote
+}
+
+// Extracts the diagnostics from a compiler build log. Lines that are not
+// recognised as the start of a diagnostic (source excerpts, caret lines,
+// summaries) are skipped.
+func ParseBuildLog(log string) []Diagnostic {
+	var diags []Diagnostic
+	for _, line := range strings.Split(log, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if m := clangDiagnostic.FindStringSubmatch(line); m != nil {
+			d := Diagnostic{File: m[1], Severity: parseSeverity(m[4]), Message: m[5]}
+			d.Line, _ = strconv.Atoi(m[2])
+			d.Column, _ = strconv.Atoi(m[3])
+			diags = append(diags, d)
+		} else if m := edgDiagnostic.FindStringSubmatch(line); m != nil {
+			d := Diagnostic{File: m[1], Severity: parseSeverity(m[3]), Message: m[4]}
+			d.Line, _ = strconv.Atoi(m[2])
+			diags = append(diags, d)
+		}
+	}
+	return diags
+}
+
+//////////////// Abstract Functions ////////////////
+
+// All diagnostics of all devices.
+func (r *BuildResult) Diagnostics() []Diagnostic {
+	var diags []Diagnostic
+	for _, dev := range r.Devices {
+		diags = append(diags, dev.Diagnostics...)
+	}
+	return diags
+}
+
+func (r *BuildResult) filter(min, max DiagnosticSeverity) []Diagnostic {
+	var diags []Diagnostic
+	for _, d := range r.Diagnostics() {
+		if d.Severity >= min && d.Severity <= max {
+			diags = append(diags, d)
+		}
+	}
+	return diags
+}
+
+// Diagnostics of severity error or fatal error.
+func (r *BuildResult) Errors() []Diagnostic {
+	return r.filter(SeverityError, SeverityFatal)
+}
+
+// Diagnostics of severity warning.
+func (r *BuildResult) Warnings() []Diagnostic {
+	return r.filter(SeverityWarning, SeverityWarning)
+}
+
+// Whether the build failed on any device.
+func (r *BuildResult) Failed() bool {
+	for _, dev := range r.Devices {
+		if dev.Status == BuildStatusError {
+			return true
+		}
+	}
+	return len(r.Errors()) > 0
+}
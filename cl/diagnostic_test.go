@@ -0,0 +1,30 @@
+package cl
+
+import "testing"
+
+func TestParseBuildLog(t *testing.T) {
+	log := "<source>:3:9: warning: unused variable 'x'\n" +
+		"    int x = 0;\n" +
+		"        ^\n" +
+		"<source>:7:1: error: use of undeclared identifier 'y'\n" +
+		"\"/tmp/k.cl\", line 12: error: identifier \"z\" is undefined\n" +
+		"1 warning and 2 errors generated.\n"
+	diags := ParseBuildLog(log)
+	if len(diags) != 3 {
+		t.Fatalf("expected 3 diagnostics, got %d: %v", len(diags), diags)
+	}
+	if d := diags[0]; d.Severity != SeverityWarning || d.File != "<source>" || d.Line != 3 || d.Column != 9 {
+		t.Errorf("unexpected warning: %+v", d)
+	}
+	if d := diags[1]; d.Severity != SeverityError || d.Line != 7 || d.Message != "use of undeclared identifier 'y'" {
+		t.Errorf("unexpected error: %+v", d)
+	}
+	if d := diags[2]; d.Severity != SeverityError || d.File != "/tmp/k.cl" || d.Line != 12 || d.Column != 0 {
+		t.Errorf("unexpected EDG error: %+v", d)
+	}
+
+	result := &BuildResult{Devices: []DeviceBuildLog{{Status: BuildStatusSuccess, Diagnostics: diags}}}
+	if len(result.Errors()) != 2 || len(result.Warnings()) != 1 || !result.Failed() {
+		t.Errorf("unexpected result summary: %d errors, %d warnings", len(result.Errors()), len(result.Warnings()))
+	}
+}
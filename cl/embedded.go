@@ -0,0 +1,61 @@
+package cl
+
+//////////////// Abstract Types ////////////////
+
+// A program shipped inside a Go binary, usually written by the clembed
+// generator: its sources and build options plus binaries precompiled for
+// known devices.
+type EmbeddedProgram struct {
+	Sources  []string
+	Options  string
+	Binaries []DeviceBinary
+}
+
+//////////////// Abstract Functions ////////////////
+
+// Whether every one of devices has a matching precompiled binary.
+func (e *EmbeddedProgram) HasBinaries(devices []*Device) bool {
+	for _, dev := range devices {
+		found := false
+		for _, b := range e.Binaries {
+			if b.Matches(dev) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return len(devices) > 0
+}
+
+// Returns the program built for devices (all devices of the context if
+// nil). When every device has a matching precompiled binary the program is
+// created from the binaries; otherwise, or if the driver rejects them, it is
+// compiled from the embedded sources with the embedded options.
+func (e *EmbeddedProgram) Load(ctx *Context, devices []*Device) (*Program, error) {
+	if len(devices) == 0 {
+		devices = ctx.devices
+	}
+	if e.HasBinaries(devices) {
+		if program, _, err := ctx.CreateProgramFromBinaries(e.Binaries, devices); err == nil {
+			if err := program.BuildProgram(devices, e.Options); err == nil {
+				return program, nil
+			}
+			program.Release()
+		}
+	}
+	if len(e.Sources) == 0 {
+		return nil, ErrNoMatchingBinary
+	}
+	program, err := ctx.CreateProgramWithSource(e.Sources)
+	if err != nil {
+		return nil, err
+	}
+	if err := program.BuildProgram(devices, e.Options); err != nil {
+		program.Release()
+		return nil, err
+	}
+	return program, nil
+}
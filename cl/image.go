@@ -311,6 +311,21 @@ func (q *CommandQueue) EnqueueFillImage(image *MemObject, color unsafe.Pointer,
         return newEvent(event), err
 }
 
+// Fills an image whose channel data type is normalized or floating point.
+func (q *CommandQueue) EnqueueFillImageFloat4(image *MemObject, color Float4, origin, region [3]int, eventWaitList []*Event) (*Event, error) {
+	return q.EnqueueFillImage(image, unsafe.Pointer(&color), origin, region, eventWaitList)
+}
+
+// Fills an image whose channel data type is an unnormalized signed integer.
+func (q *CommandQueue) EnqueueFillImageInt4(image *MemObject, color Int4, origin, region [3]int, eventWaitList []*Event) (*Event, error) {
+	return q.EnqueueFillImage(image, unsafe.Pointer(&color), origin, region, eventWaitList)
+}
+
+// Fills an image whose channel data type is an unnormalized unsigned integer.
+func (q *CommandQueue) EnqueueFillImageUint4(image *MemObject, color Uint4, origin, region [3]int, eventWaitList []*Event) (*Event, error) {
+	return q.EnqueueFillImage(image, unsafe.Pointer(&color), origin, region, eventWaitList)
+}
+
 // Enqueues a command to copy from a 2D or 3D image object to device memory as image.
 func (q *CommandQueue) EnqueueCopyImage(dst, src *MemObject, dst_origin, src_origin, region [3]int, eventWaitList []*Event) (*Event, error) {
 	dOrigin := sizeT3(dst_origin)
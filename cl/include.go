@@ -0,0 +1,148 @@
+package cl
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//////////////// Basic Functions ////////////////
+
+var includeDirective = regexp.MustCompile(`^\s*#\s*include\s*([<"])([^>"]+)[>"]`)
+
+// Looks up an included file the way a C preprocessor would: quoted names
+// relative to the including file first, then every name relative to the
+// root of fsys.
+func resolveInclude(fsys fs.FS, from, name string, quoted bool) (string, []byte, bool) {
+	var candidates []string
+	if quoted {
+		candidates = append(candidates, path.Join(path.Dir(from), name))
+	}
+	candidates = append(candidates, path.Clean(name))
+	for _, c := range candidates {
+		if !fs.ValidPath(c) {
+			continue
+		}
+		if data, err := fs.ReadFile(fsys, c); err == nil {
+			return c, data, true
+		}
+	}
+	return "", nil, false
+}
+
+// Reads the program source at name from fsys along with every file it
+// includes, directly or through other headers. Headers are keyed by the
+// name used in the #include directive and, when different, by their path
+// in fsys. Includes that cannot be found are returned as diagnostics.
+// Conditional compilation is not evaluated, so files named in disabled
+// #if blocks must exist as well.
+func readIncludes(fsys fs.FS, name string) (string, map[string]string, []Diagnostic, error) {
+	main, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	headers := map[string]string{}
+	var diags []Diagnostic
+	seen := map[string]bool{name: true}
+	queue := []string{name}
+	sources := map[string]string{name: string(main)}
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+		for i, line := range strings.Split(sources[file], "\n") {
+			m := includeDirective.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			spelling := m[2]
+			resolved, data, ok := resolveInclude(fsys, file, spelling, m[1] == `"`)
+			if !ok {
+				diags = append(diags, Diagnostic{Severity: SeverityFatal, File: file, Line: i + 1,
+					Message: fmt.Sprintf("'%s' file not found", spelling)})
+				continue
+			}
+			for _, key := range []string{spelling, resolved} {
+				if prev, dup := headers[key]; dup && prev != string(data) {
+					diags = append(diags, Diagnostic{Severity: SeverityError, File: file, Line: i + 1,
+						Message: fmt.Sprintf("'%s' refers to different files depending on the including file", key)})
+				}
+				headers[key] = string(data)
+			}
+			if !seen[resolved] {
+				seen[resolved] = true
+				sources[resolved] = string(data)
+				queue = append(queue, resolved)
+			}
+		}
+	}
+	return string(main), headers, diags, nil
+}
+
+func releaseHeaders(headers []*ProgramHeaders) {
+	for _, h := range headers {
+		h.codes.Release()
+	}
+}
+
+//////////////// Abstract Functions ////////////////
+
+// Compiles the program at name in fsys (for instance an embed.FS), resolving
+// its #include directives against fsys. Each included file is handed to the
+// compiler as a header program, so the driver never needs to see the files
+// on disk. Missing includes are reported as a BuildError carrying their
+// diagnostics before anything is sent to the compiler. The returned program
+// is a compiled object that still has to be linked; see BuildProgramFS.
+func (ctx *Context) CompileProgramFS(fsys fs.FS, name string, devices []*Device, options string) (*Program, error) {
+	source, included, diags, err := readIncludes(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(diags) > 0 {
+		log := make([]string, len(diags))
+		for i, d := range diags {
+			log[i] = d.String()
+		}
+		result := &BuildResult{Devices: []DeviceBuildLog{{Status: BuildStatusError, Log: strings.Join(log, "\n"), Diagnostics: diags}}}
+		return nil, BuildError{Message: result.Devices[0].Log, Result: result}
+	}
+
+	var headers []*ProgramHeaders
+	defer func() { releaseHeaders(headers) }()
+	names := make([]string, 0, len(included))
+	for includeName := range included {
+		names = append(names, includeName)
+	}
+	sort.Strings(names)
+	for _, includeName := range names {
+		header, err := ctx.CreateProgramWithSource([]string{included[includeName]})
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, NewProgramHeaders(header, includeName))
+	}
+
+	program, err := ctx.CreateProgramWithSource([]string{source})
+	if err != nil {
+		return nil, err
+	}
+	if err := program.CompileProgram(devices, options, headers); err != nil {
+		program.Release()
+		return nil, err
+	}
+	return program, nil
+}
+
+// Compiles the program at name in fsys with CompileProgramFS and links it
+// into an executable. compileOptions and linkOptions are passed to the
+// compiler and the linker respectively.
+func (ctx *Context) BuildProgramFS(fsys fs.FS, name string, devices []*Device, compileOptions, linkOptions string) (*Program, error) {
+	object, err := ctx.CompileProgramFS(fsys, name, devices, compileOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer object.Release()
+	return ctx.LinkProgram([]*Program{object}, devices, linkOptions)
+}
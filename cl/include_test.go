@@ -0,0 +1,30 @@
+package cl
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadIncludes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"kernels/main.cl":  {Data: []byte("#include \"common.h\"\n#include <math/consts.h>\n__kernel void k() {}\n")},
+		"kernels/common.h": {Data: []byte("  #  include \"types.h\"\n")},
+		"kernels/types.h":  {Data: []byte("typedef float real;\n")},
+		"math/consts.h":    {Data: []byte("#define PI 3.14159f\n#include \"missing.h\"\n")},
+	}
+	source, headers, diags, err := readIncludes(fsys, "kernels/main.cl")
+	if err != nil {
+		t.Fatalf("readIncludes failed: %+v", err)
+	}
+	if source != string(fsys["kernels/main.cl"].Data) {
+		t.Errorf("unexpected main source %q", source)
+	}
+	for _, name := range []string{"common.h", "kernels/common.h", "types.h", "kernels/types.h", "math/consts.h"} {
+		if _, ok := headers[name]; !ok {
+			t.Errorf("header %q not registered", name)
+		}
+	}
+	if len(diags) != 1 || diags[0].File != "math/consts.h" || diags[0].Line != 2 || diags[0].Severity != SeverityFatal {
+		t.Errorf("unexpected diagnostics: %v", diags)
+	}
+}
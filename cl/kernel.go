@@ -24,6 +24,9 @@ import "C"
 
 import (
 	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
 	"unsafe"
 )
 
@@ -39,13 +42,26 @@ func (e ErrUnsupportedArgumentType) Error() string {
 
 //////////////// Abstract Types ////////////////
 type Kernel struct {
-	clKernel C.cl_kernel
-	name     string
+	clKernel  C.cl_kernel
+	name      string
+	argInfo   []kernelArgInfo
+	argIndex  map[string]int
+	localArgs map[int]int // sizes of the __local arguments set so far
+	mu        sync.Mutex  // held by EnqueueKernelArgs only, not by SetArg or SetArgByName
+
+	// CL_KERNEL_LOCAL_MEM_SIZE per device, queried when the kernel was
+	// created and no __local argument was set yet
+	staticLocal map[C.cl_device_id]int64
 }
 
 //////////////// Golang Types ////////////////
 type LocalBuffer int
 
+// Implemented by *MemObject and by every typed Buffer through embedding.
+type memObjectArg interface {
+	memObject() *MemObject
+}
+
 ////////////////// Supporting Types ////////////////
 type CL_go_native_kernel func(user_data unsafe.Pointer)
 var go_native_kernel_func map[unsafe.Pointer]CL_go_native_kernel
@@ -93,96 +109,131 @@ func (k *Kernel) SetArgs(args ...interface{}) error {
 	return nil
 }
 
+// Sets the argument at index from a Go value. Go scalar types map onto the
+// OpenCL C scalar of the same width (int and uint keep the width Go gives
+// them, so they are long/ulong on 64-bit hosts), bool is passed as an int
+// holding 0 or 1, *MemObject and typed buffers as __global or __constant
+// pointers, *Sampler as sampler_t and LocalBuffer as a __local allocation of
+// the given size in bytes. Arrays of 2, 3, 4, 8 or 16 scalars are passed as
+// vectors and structs are passed by value, laid out with the OpenCL C
+// alignment rules rather than Go's. Nil buffers and samplers fail with
+// ErrInvalidMemObject and ErrInvalidSampler. With SetArgValidation enabled
+// the value is first checked against the declared type of the argument.
 func (k *Kernel) SetArg(index int, arg interface{}) error {
+	if k.argInfo != nil {
+		if err := k.validateArg(index, arg); err != nil {
+			return err
+		}
+	}
 	switch val := arg.(type) {
 	case uint8:
 		return k.SetArgUint8(index, val)
 	case int8:
 		return k.SetArgInt8(index, val)
+	case uint16:
+		return k.SetArgUint16(index, val)
+	case int16:
+		return k.SetArgInt16(index, val)
 	case uint32:
 		return k.SetArgUint32(index, val)
 	case uint64:
 		return k.SetArgUint64(index, val)
 	case int32:
 		return k.SetArgInt32(index, val)
+	case int64:
+		return k.SetArgInt64(index, val)
+	case int:
+		return k.SetArgInt(index, val)
+	case uint:
+		return k.SetArgUint(index, val)
+	case bool:
+		return k.SetArgBool(index, val)
 	case float32:
 		return k.SetArgFloat32(index, val)
+	case float64:
+		return k.SetArgFloat64(index, val)
 	case *MemObject:
 		return k.SetArgBuffer(index, val)
+	case memObjectArg:
+		// A nil typed buffer cannot reach its embedded *MemObject
+		if v := reflect.ValueOf(val); v.Kind() == reflect.Ptr && v.IsNil() {
+			return ErrInvalidMemObject
+		}
+		return k.SetArgBuffer(index, val.memObject())
+	case *Sampler:
+		return k.SetArgSampler(index, val)
 	case LocalBuffer:
 		return k.SetArgLocal(index, int(val))
 	default:
+		return k.setArgByValue(index, arg)
+	}
+}
+
+// Handles named scalar types, vectors and structs.
+func (k *Kernel) setArgByValue(index int, arg interface{}) error {
+	t := reflect.TypeOf(arg)
+	if t == nil {
 		return ErrUnsupportedArgumentType{Index: index, Value: arg}
 	}
+	switch {
+	case t.Kind() == reflect.Bool:
+		return k.SetArgBool(index, reflect.ValueOf(arg).Bool())
+	case isScalarKind(t.Kind()), t.Kind() == reflect.Array, t.Kind() == reflect.Struct:
+		buf, err := marshalArg(arg)
+		if err != nil {
+			return err
+		}
+		return k.SetArgUnsafe(index, len(buf), unsafe.Pointer(&buf[0]))
+	}
+	return ErrUnsupportedArgumentType{Index: index, Value: arg}
 }
 
+// Address space of the argument at index as a string, see ArgAddress.
 func (k *Kernel) ArgAddressQualifier(index int) (string, error) {
-        var val C.cl_kernel_arg_address_qualifier
-        var err C.cl_int
-        defer C.free(unsafe.Pointer(&err))
-        if err = C.clGetKernelArgInfo(k.clKernel, C.cl_uint(index), C.CL_KERNEL_ARG_ADDRESS_QUALIFIER, C.size_t(unsafe.Sizeof(val)), unsafe.Pointer(&val), nil); err != C.CL_SUCCESS {
-                return "", toError(err)
-        }
-        switch val {
-        default:
-                return "", toError(err)
-        case C.CL_KERNEL_ARG_ADDRESS_GLOBAL:
-                return "Global", nil
-        case C.CL_KERNEL_ARG_ADDRESS_LOCAL:
-                return "Local", nil
-        case C.CL_KERNEL_ARG_ADDRESS_CONSTANT:
-                return "Constant", nil
-        case C.CL_KERNEL_ARG_ADDRESS_PRIVATE:
-                return "Private", nil
-        }
+	val, err := k.ArgAddress(index)
+	if err != nil {
+		return "", err
+	}
+	return val.String(), nil
 }
 
+// Access qualifier of the argument at index as a string, see ArgAccess.
 func (k *Kernel) ArgAccessQualifier(index int) (string, error) {
-        var val C.cl_kernel_arg_access_qualifier
-	var err C.cl_int
-	defer C.free(unsafe.Pointer(&err))
-        if err = C.clGetKernelArgInfo(k.clKernel, C.cl_uint(index), C.CL_KERNEL_ARG_ACCESS_QUALIFIER, C.size_t(unsafe.Sizeof(val)), unsafe.Pointer(&val), nil); err != C.CL_SUCCESS {
-                return "", toError(err)
-        }
-	switch val {
-	default:
-		return "", toError(err)
-	case C.CL_KERNEL_ARG_ACCESS_READ_ONLY:
-		return "ReadOnly", nil
-	case C.CL_KERNEL_ARG_ACCESS_READ_WRITE:
-		return "ReadWrite", nil
-	case C.CL_KERNEL_ARG_ACCESS_WRITE_ONLY:
-		return "WriteOnly", nil
-	case C.CL_KERNEL_ARG_ACCESS_NONE:
-		return "None", nil
+	val, err := k.ArgAccess(index)
+	if err != nil {
+		return "", err
 	}
+	return val.String(), nil
 }
 
-func (k *Kernel) ArgTypeQualifier(index int) (C.cl_kernel_arg_type_qualifier, error) {
-        var val C.cl_kernel_arg_type_qualifier
-        err := C.clGetKernelArgInfo(k.clKernel, C.cl_uint(index), C.CL_KERNEL_ARG_TYPE_QUALIFIER, C.size_t(unsafe.Sizeof(val)), unsafe.Pointer(&val), nil)
-        return val, toError(err)
+func (k *Kernel) getArgInfoString(index int, param C.cl_kernel_arg_info) (string, error) {
+	var size C.size_t
+	if err := C.clGetKernelArgInfo(k.clKernel, C.cl_uint(index), param, 0, nil, &size); err != C.CL_SUCCESS {
+		return "", toError(err)
+	}
+	if size <= 1 {
+		return "", nil
+	}
+	buf := make([]byte, size)
+	if err := C.clGetKernelArgInfo(k.clKernel, C.cl_uint(index), param, size, unsafe.Pointer(&buf[0]), nil); err != C.CL_SUCCESS {
+		return "", toError(err)
+	}
+	// Drop the NUL terminator included in size
+	return string(buf[:size-1]), nil
 }
 
 func (k *Kernel) ArgName(index int) (string, error) {
-        var strC [1024]byte
-        var strN C.size_t
-        if err := C.clGetKernelArgInfo(k.clKernel, C.cl_uint(index), C.CL_KERNEL_ARG_NAME, 1024, unsafe.Pointer(&strC[0]), &strN); err != C.CL_SUCCESS {
-                return "", toError(err)
-        }
-        return string(strC[:strN]), nil
+	return k.getArgInfoString(index, C.CL_KERNEL_ARG_NAME)
 }
 
 func (k *Kernel) ArgTypeName(index int) (string, error) {
-        var strC [1024]byte
-        var strN C.size_t
-        if err := C.clGetKernelArgInfo(k.clKernel, C.cl_uint(index), C.CL_KERNEL_ARG_TYPE_NAME, 1024, unsafe.Pointer(&strC[0]), &strN); err != C.CL_SUCCESS {
-                return "", toError(err)
-        }
-        return string(strC[:strN]), nil
+	return k.getArgInfoString(index, C.CL_KERNEL_ARG_TYPE_NAME)
 }
 
 func (k *Kernel) SetArgBuffer(index int, buffer *MemObject) error {
+	if buffer == nil {
+		return ErrInvalidMemObject
+	}
 	return k.SetArgUnsafe(index, int(unsafe.Sizeof(buffer.clMem)), unsafe.Pointer(&buffer.clMem))
 }
 
@@ -210,13 +261,65 @@ func (k *Kernel) SetArgUint64(index int, val uint64) error {
 	return k.SetArgUnsafe(index, int(unsafe.Sizeof(val)), unsafe.Pointer(&val))
 }
 
+func (k *Kernel) SetArgInt16(index int, val int16) error {
+	return k.SetArgUnsafe(index, int(unsafe.Sizeof(val)), unsafe.Pointer(&val))
+}
+
+func (k *Kernel) SetArgUint16(index int, val uint16) error {
+	return k.SetArgUnsafe(index, int(unsafe.Sizeof(val)), unsafe.Pointer(&val))
+}
+
+func (k *Kernel) SetArgInt64(index int, val int64) error {
+	return k.SetArgUnsafe(index, int(unsafe.Sizeof(val)), unsafe.Pointer(&val))
+}
+
+func (k *Kernel) SetArgFloat64(index int, val float64) error {
+	return k.SetArgUnsafe(index, int(unsafe.Sizeof(val)), unsafe.Pointer(&val))
+}
+
+func (k *Kernel) SetArgInt(index int, val int) error {
+	return k.SetArgUnsafe(index, int(unsafe.Sizeof(val)), unsafe.Pointer(&val))
+}
+
+func (k *Kernel) SetArgUint(index int, val uint) error {
+	return k.SetArgUnsafe(index, int(unsafe.Sizeof(val)), unsafe.Pointer(&val))
+}
+
+// OpenCL C does not allow bool kernel arguments, so val is passed as an int.
+func (k *Kernel) SetArgBool(index int, val bool) error {
+	var i int32
+	if val {
+		i = 1
+	}
+	return k.SetArgInt32(index, i)
+}
+
+func (k *Kernel) SetArgSampler(index int, sampler *Sampler) error {
+	if sampler == nil {
+		return ErrInvalidSampler
+	}
+	return k.SetArgUnsafe(index, int(unsafe.Sizeof(sampler.clSampler)), unsafe.Pointer(&sampler.clSampler))
+}
+
 func (k *Kernel) SetArgLocal(index int, size int) error {
 	return k.SetArgUnsafe(index, size, nil)
 }
 
 func (k *Kernel) SetArgUnsafe(index, argSize int, arg unsafe.Pointer) error {
 	//fmt.Println("FUNKY: ", index, argSize)
-	return toError(C.clSetKernelArg(k.clKernel, C.cl_uint(index), C.size_t(argSize), arg))
+	if err := toError(C.clSetKernelArg(k.clKernel, C.cl_uint(index), C.size_t(argSize), arg)); err != nil {
+		return err
+	}
+	// A nil value allocates local memory of argSize bytes
+	if arg == nil {
+		if k.localArgs == nil {
+			k.localArgs = make(map[int]int)
+		}
+		k.localArgs[index] = argSize
+	} else {
+		delete(k.localArgs, index)
+	}
+	return nil
 }
 
 func (k *Kernel) GlobalWorkGroupSize(device *Device) ([3]int, error) {
@@ -241,7 +344,6 @@ func (k *Kernel) PreferredWorkGroupSizeMultiple(device *Device) (int, error) {
 
 func (k *Kernel) CompileWorkGroupSize(device *Device) ([3]int, error) {
 	var wgSize [3]C.size_t
-	defer C.free(unsafe.Pointer(&wgSize))
         if err := C.clGetKernelWorkGroupInfo(k.clKernel, device.nullableId(), C.CL_KERNEL_COMPILE_WORK_GROUP_SIZE, C.size_t(unsafe.Sizeof(wgSize)), unsafe.Pointer(&wgSize), nil); err != C.CL_SUCCESS {
 		return [3]int{-1, -1, -1}, toError(err)
 	}
@@ -272,16 +374,28 @@ func (k *Kernel) ReferenceCount() (int, error) {
 	return int(num), toError(err)
 }
 
+func (k *Kernel) getInfoString(param C.cl_kernel_info) (string, error) {
+	var size C.size_t
+	if err := C.clGetKernelInfo(k.clKernel, param, 0, nil, &size); err != C.CL_SUCCESS {
+		return "", toError(err)
+	}
+	if size <= 1 {
+		return "", nil
+	}
+	buf := make([]byte, size)
+	if err := C.clGetKernelInfo(k.clKernel, param, size, unsafe.Pointer(&buf[0]), nil); err != C.CL_SUCCESS {
+		return "", toError(err)
+	}
+	// Drop the NUL terminator included in size
+	return string(buf[:size-1]), nil
+}
+
 func (k *Kernel) FunctionName() (string, error) {
-        var name C.char
-	err := C.clGetKernelInfo(k.clKernel, C.CL_KERNEL_FUNCTION_NAME, C.size_t(unsafe.Sizeof(name)), unsafe.Pointer(&name), nil)
-        return C.GoString(&name), toError(err)
+	return k.getInfoString(C.CL_KERNEL_FUNCTION_NAME)
 }
 
 func (k *Kernel) Attributes() (string, error) {
-        var name C.char
-	err := C.clGetKernelInfo(k.clKernel, C.CL_KERNEL_ATTRIBUTES, C.size_t(unsafe.Sizeof(name)), unsafe.Pointer(&name), nil)
-        return C.GoString(&name), toError(err)
+	return k.getInfoString(C.CL_KERNEL_ATTRIBUTES)
 }
 
 func (k *Kernel) Context() (*Context, error) {
@@ -350,31 +464,71 @@ func (q *CommandQueue) EnqueueNativeKernel(user_args unsafe.Pointer, num_user_ar
 	return newEvent(event), err
 }
 
-func (p *Program) CreateKernelsInProgram() ([]*Kernel, error) {
-	var num_kerns C.cl_uint
-	err := C.clCreateKernelsInProgram(p.clProgram, 1, nil, &num_kerns)
-	if toError(err) != nil {
-		fmt.Printf("Error getting number of kernels to create \n")
+// Creates a kernel for every kernel function of the built program, keyed by
+// function name.
+func (p *Program) CreateKernelsInProgram() (map[string]*Kernel, error) {
+	var numKernels C.cl_uint
+	if err := C.clCreateKernelsInProgram(p.clProgram, 0, nil, &numKernels); err != C.CL_SUCCESS {
 		return nil, toError(err)
 	}
-	kernel_list := make([]C.cl_kernel, int(num_kerns))
-	err = C.clCreateKernelsInProgram(p.clProgram, num_kerns, &kernel_list[0], nil)
-	if toError(err) != nil {
-		fmt.Printf("Error creating kernels \n")
+	kernels := make(map[string]*Kernel, int(numKernels))
+	if numKernels == 0 {
+		return kernels, nil
+	}
+	kernelList := make([]C.cl_kernel, int(numKernels))
+	if err := C.clCreateKernelsInProgram(p.clProgram, numKernels, &kernelList[0], nil); err != C.CL_SUCCESS {
 		return nil, toError(err)
 	}
-	returnKerns := make([]*Kernel, len(kernel_list))
-	for i, kptr := range kernel_list {
-		testKern := &Kernel{clKernel: kptr, name: ""}
-		kname, errK := testKern.FunctionName()
-		if errK == nil {
-			returnKerns[i].clKernel = kptr
-			returnKerns[i].name = kname
-		} else {
-			fmt.Printf("Error getting information about kernel %d \n", i)
-			returnKerns[i] = nil
+	for i, clKernel := range kernelList {
+		kernel := &Kernel{clKernel: clKernel}
+		runtime.SetFinalizer(kernel, releaseKernel)
+		name, err := kernel.FunctionName()
+		if err != nil {
+			for _, k := range kernelList[i:] {
+				C.clReleaseKernel(k)
+			}
+			kernel.clKernel = nil
+			for _, k := range kernels {
+				k.Release()
+			}
+			return nil, err
+		}
+		kernel.name = name
+		kernel.recordStaticLocalMem(p)
+		kernels[name] = kernel
+	}
+	return kernels, nil
+}
+
+// Records the local memory the new kernel declares itself on each device of
+// p, before any __local argument adds to it.
+func (k *Kernel) recordStaticLocalMem(p *Program) {
+	devices := p.devices
+	if len(devices) == 0 {
+		devices, _ = p.GetDevices()
+	}
+	for _, device := range devices {
+		size, err := k.WorkGroupLocalMemSize(device)
+		if err != nil {
+			continue
+		}
+		if k.staticLocal == nil {
+			k.staticLocal = make(map[C.cl_device_id]int64, len(devices))
 		}
+		k.staticLocal[device.nullableId()] = int64(size)
+	}
+}
+
+// The local memory the kernel declares itself on device, given the size the
+// driver reports now. Without a size recorded at creation, the reported
+// size is only known to be static while no __local argument is set.
+func (k *Kernel) staticLocalMemSize(device *Device, reported int64) int64 {
+	if size, ok := k.staticLocal[device.nullableId()]; ok {
+		return size
+	}
+	if len(k.localArgs) == 0 {
+		return reported
 	}
-	return returnKerns, nil
+	return 0
 }
 
@@ -0,0 +1,231 @@
+package cl
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidGlobalSize = errors.New("cl: global size must have 1 to 3 positive dimensions")
+
+//////////////// Abstract Types ////////////////
+
+// Work sizes chosen for launching a kernel on a device. Extent is the range
+// the caller asked for; Global is Extent padded up to a multiple of Local,
+// so work-items past Extent must return early.
+type LaunchConfig struct {
+	Extent []int
+	Global []int
+	Local  []int
+}
+
+// What limits the work-group size of a kernel on a device.
+type workGroupLimits struct {
+	maxSize  int    // work-items per group for this kernel on this device
+	maxItems []int  // work-items per group along each dimension
+	multiple int    // preferred multiple of the work-group size
+	required [3]int // reqd_work_group_size, all zero if not declared
+}
+
+//////////////// Basic Functions ////////////////
+
+func roundUp(n, multiple int) int {
+	return (n + multiple - 1) / multiple * multiple
+}
+
+// Parses reqd_work_group_size(x,y,z) out of the kernel attributes string.
+func parseReqdWorkGroupSize(attributes string) ([3]int, bool) {
+	const attr = "reqd_work_group_size("
+	i := strings.Index(attributes, attr)
+	if i < 0 {
+		return [3]int{}, false
+	}
+	rest := attributes[i+len(attr):]
+	end := strings.IndexByte(rest, ')')
+	if end < 0 {
+		return [3]int{}, false
+	}
+	var size [3]int
+	parts := strings.Split(rest[:end], ",")
+	if len(parts) != 3 {
+		return [3]int{}, false
+	}
+	for j, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || n <= 0 {
+			return [3]int{}, false
+		}
+		size[j] = n
+	}
+	return size, true
+}
+
+// Picks the local size for a range of global work-items. A required size is
+// used as is. Otherwise one dimension gets the size that wastes the fewest
+// padded work-items, preferring larger groups; with more dimensions the
+// first starts at the preferred multiple and the smallest dimension is then
+// doubled while the group still fits the limits and the range.
+func chooseLocalSize(global []int, lim workGroupLimits) []int {
+	dims := len(global)
+	local := make([]int, dims)
+	if lim.required != [3]int{} {
+		copy(local, lim.required[:dims])
+		return local
+	}
+	maxSize := lim.maxSize
+	if maxSize < 1 {
+		maxSize = 1
+	}
+	multiple := lim.multiple
+	if multiple < 1 || multiple > maxSize {
+		multiple = 1
+	}
+	maxItems := func(d int) int {
+		if d < len(lim.maxItems) && lim.maxItems[d] > 0 {
+			return lim.maxItems[d]
+		}
+		return maxSize
+	}
+
+	if dims == 1 {
+		limit := maxSize
+		if m := maxItems(0); m < limit {
+			limit = m
+		}
+		if g := roundUp(global[0], multiple); g < limit {
+			limit = g
+		}
+		step := multiple
+		if limit < step {
+			step = 1
+		}
+		best, bestPadded := step, roundUp(global[0], step)
+		for l := step; l <= limit; l += step {
+			if padded := roundUp(global[0], l); padded <= bestPadded {
+				best, bestPadded = l, padded
+			}
+		}
+		local[0] = best
+		return local
+	}
+
+	for d := range local {
+		local[d] = 1
+	}
+	size := 1
+	if multiple <= maxItems(0) && multiple <= global[0] {
+		local[0], size = multiple, multiple
+	}
+	for {
+		// Grow the smallest dimension that can still grow, keeping groups square
+		grow := -1
+		for d := 0; d < dims; d++ {
+			if size*2 <= maxSize && local[d]*2 <= maxItems(d) && local[d] < global[d] && (grow < 0 || local[d] < local[grow]) {
+				grow = d
+			}
+		}
+		if grow < 0 {
+			return local
+		}
+		local[grow] *= 2
+		size *= 2
+	}
+}
+
+// Pads global up to a multiple of local in every dimension.
+func padGlobalSize(global, local []int) []int {
+	padded := make([]int, len(global))
+	for d := range global {
+		padded[d] = roundUp(global[d], local[d])
+	}
+	return padded
+}
+
+//////////////// Abstract Functions ////////////////
+
+// The device of q, queried if q was not created by CreateCommandQueue.
+func queueDevice(q *CommandQueue) (*Device, error) {
+	if q.device != nil {
+		return q.device, nil
+	}
+	return q.GetQueueDevice()
+}
+
+func (k *Kernel) workGroupLimits(device *Device) (workGroupLimits, error) {
+	var lim workGroupLimits
+	var err error
+	if lim.maxSize, err = k.WorkGroupSize(device); err != nil {
+		return lim, err
+	}
+	if deviceMax := device.MaxWorkGroupSize(); deviceMax > 0 && deviceMax < lim.maxSize {
+		lim.maxSize = deviceMax
+	}
+	lim.maxItems = device.MaxWorkItemSizes()
+	if lim.multiple, err = k.PreferredWorkGroupSizeMultiple(device); err != nil {
+		return lim, err
+	}
+	if required, err := k.CompileWorkGroupSize(device); err == nil && required[0] > 0 {
+		lim.required = required
+	} else if attributes, err := k.Attributes(); err == nil {
+		lim.required, _ = parseReqdWorkGroupSize(attributes)
+	}
+	return lim, nil
+}
+
+// Chooses a local size for running the kernel over global work-items on
+// device, honouring a reqd_work_group_size declared by the kernel, and pads
+// the global size to a multiple of it. Fails with ErrLocalMemoryExceeded if
+// the local memory the kernel needs, including its LocalBuffer arguments,
+// does not fit on the device.
+func (k *Kernel) LaunchConfig(device *Device, global []int) (*LaunchConfig, error) {
+	if len(global) == 0 || len(global) > 3 {
+		return nil, ErrInvalidGlobalSize
+	}
+	for _, g := range global {
+		if g <= 0 {
+			return nil, ErrInvalidGlobalSize
+		}
+	}
+	if err := k.CheckLocalMemory(device); err != nil {
+		return nil, err
+	}
+	lim, err := k.workGroupLimits(device)
+	if err != nil {
+		return nil, err
+	}
+	local := chooseLocalSize(global, lim)
+	return &LaunchConfig{
+		Extent: append([]int(nil), global...),
+		Global: padGlobalSize(global, local),
+		Local:  local,
+	}, nil
+}
+
+// Enqueues the kernel over global work-items with a local size chosen by
+// LaunchConfig for the device of the queue. When the global size has to be
+// padded the kernel runs on more work-items than asked for; if extentArg is
+// not negative, the real extent is passed to the kernel beforehand as int
+// arguments extentArg, extentArg+1, ... (one per dimension) so it can skip
+// the padding.
+func (q *CommandQueue) EnqueueKernel(kernel *Kernel, global []int, extentArg int, eventWaitList []*Event) (*Event, *LaunchConfig, error) {
+	device, err := queueDevice(q)
+	if err != nil {
+		return nil, nil, err
+	}
+	config, err := kernel.LaunchConfig(device, global)
+	if err != nil {
+		return nil, nil, err
+	}
+	if extentArg >= 0 {
+		for d, e := range config.Extent {
+			if err := kernel.SetArg(extentArg+d, int32(e)); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	event, err := q.EnqueueNDRangeKernel(kernel, nil, config.Global, config.Local, eventWaitList)
+	if err != nil {
+		return nil, nil, err
+	}
+	return event, config, nil
+}
@@ -0,0 +1,41 @@
+package cl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChooseLocalSize(t *testing.T) {
+	lim := workGroupLimits{maxSize: 256, maxItems: []int{1024, 1024, 64}, multiple: 32}
+	cases := []struct {
+		global []int
+		lim    workGroupLimits
+		local  []int
+		padded []int
+	}{
+		{[]int{1 << 20}, lim, []int{256}, []int{1 << 20}},
+		{[]int{1000}, lim, []int{256}, []int{1024}},
+		{[]int{10}, lim, []int{32}, []int{32}},
+		{[]int{1024, 1024}, lim, []int{32, 8}, []int{1024, 1024}},
+		{[]int{100, 3}, lim, []int{64, 4}, []int{128, 4}},
+		{[]int{64, 64, 64}, lim, []int{32, 4, 2}, []int{64, 64, 64}},
+		{[]int{1000, 1000}, workGroupLimits{maxSize: 256, required: [3]int{16, 16, 1}}, []int{16, 16}, []int{1008, 1008}},
+	}
+	for _, c := range cases {
+		local := chooseLocalSize(c.global, c.lim)
+		padded := padGlobalSize(c.global, local)
+		if !reflect.DeepEqual(local, c.local) || !reflect.DeepEqual(padded, c.padded) {
+			t.Errorf("%v: got local %v global %v, expected %v %v", c.global, local, padded, c.local, c.padded)
+		}
+	}
+}
+
+func TestParseReqdWorkGroupSize(t *testing.T) {
+	size, ok := parseReqdWorkGroupSize("vec_type_hint(float4) reqd_work_group_size(8, 4, 1)")
+	if !ok || size != [3]int{8, 4, 1} {
+		t.Errorf("got %v %v", size, ok)
+	}
+	if _, ok := parseReqdWorkGroupSize("vec_type_hint(float4)"); ok {
+		t.Errorf("found a size in attributes without one")
+	}
+}
@@ -0,0 +1,106 @@
+package cl
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrNotALibrary = errors.New("cl: linking with -create-library did not produce a library")
+
+//////////////// Abstract Types ////////////////
+
+// A reusable set of OpenCL C functions compiled once for a set of devices
+// and linked into a library (-create-library). Executables are linked
+// against one or more libraries on demand with Link or BuildExecutable,
+// without recompiling the library sources.
+type Library struct {
+	ctx     *Context
+	devices []*Device
+	object  *Program
+	library *Program
+}
+
+//////////////// Abstract Functions ////////////////
+
+// Compiles sources for devices (all devices of the context if nil) with
+// compileOptions and the given headers, then links the compiled object into
+// a library. Compile and link failures are returned as a BuildError whose
+// Result holds the log of every device.
+func (ctx *Context) CreateLibrary(sources []string, devices []*Device, compileOptions string, headers []*ProgramHeaders) (*Library, error) {
+	if len(devices) == 0 {
+		devices = ctx.devices
+	}
+	object, err := ctx.CreateProgramWithSource(sources)
+	if err != nil {
+		return nil, err
+	}
+	if err := object.CompileProgram(devices, compileOptions, headers); err != nil {
+		object.Release()
+		return nil, err
+	}
+	library, err := ctx.LinkProgram([]*Program{object}, devices, string(FlagCreateLibrary))
+	if err != nil {
+		object.Release()
+		return nil, err
+	}
+	for _, dev := range devices {
+		binType, err := library.GetProgramBinaryType(dev)
+		if err == nil && binType != ProgramBinaryTypeLibrary {
+			err = fmt.Errorf("%w on %s", ErrNotALibrary, dev.Name())
+		}
+		if err != nil {
+			library.Release()
+			object.Release()
+			return nil, err
+		}
+	}
+	return &Library{ctx: ctx, devices: devices, object: object, library: library}, nil
+}
+
+// Devices the library was compiled for.
+func (l *Library) Devices() []*Device {
+	return l.devices
+}
+
+// The compiled object the library was linked from.
+func (l *Library) Object() *Program {
+	return l.object
+}
+
+// The library program itself, for use with Context.LinkProgram.
+func (l *Library) Program() *Program {
+	return l.library
+}
+
+func (l *Library) Release() {
+	l.library.Release()
+	l.object.Release()
+}
+
+// Links the compiled objects into an executable together with this library
+// and any further libraries. The executable is built for the devices of the
+// library, which the objects and other libraries must have been compiled
+// for as well. A failed link is returned as a BuildError whose Result
+// reports the status and diagnostics of each device.
+func (l *Library) Link(objects []*Program, linkOptions string, libraries ...*Library) (*Program, error) {
+	programs := append([]*Program(nil), objects...)
+	programs = append(programs, l.library)
+	for _, lib := range libraries {
+		programs = append(programs, lib.library)
+	}
+	return l.ctx.LinkProgram(programs, l.devices, linkOptions)
+}
+
+// Compiles sources and links them against the library and any further
+// libraries into an executable. See Link.
+func (l *Library) BuildExecutable(sources []string, compileOptions, linkOptions string, libraries ...*Library) (*Program, error) {
+	object, err := l.ctx.CreateProgramWithSource(sources)
+	if err != nil {
+		return nil, err
+	}
+	defer object.Release()
+	if err := object.CompileProgram(l.devices, compileOptions, nil); err != nil {
+		return nil, err
+	}
+	return l.Link([]*Program{object}, linkOptions, libraries...)
+}
@@ -184,6 +184,10 @@ func (mb *MappedMemObject) SlicePitch() int {
 	return mb.slicePitch
 }
 
+func (b *MemObject) memObject() *MemObject {
+	return b
+}
+
 func (b *MemObject) Retain() {
         retainMemObject(b)
 }
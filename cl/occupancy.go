@@ -0,0 +1,175 @@
+package cl
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+//////////////// Basic Types ////////////////
+
+// Returned when the local memory a kernel needs per work-group, including
+// its LocalBuffer arguments, exceeds the local memory of the device. It
+// matches ErrOutOfResources with errors.Is.
+type ErrLocalMemoryExceeded struct {
+	Kernel    string
+	Device    string
+	Required  int64
+	Available int64
+}
+
+func (e ErrLocalMemoryExceeded) Error() string {
+	return fmt.Sprintf("cl: kernel %s needs %d bytes of local memory per work-group, %s has %d", e.Kernel, e.Required, e.Device, e.Available)
+}
+
+func (e ErrLocalMemoryExceeded) Unwrap() error {
+	return ErrOutOfResources
+}
+
+//////////////// Abstract Types ////////////////
+
+// Resources a kernel uses on a device and an estimate of how many of its
+// work-groups can be resident at once. OpenCL does not expose register
+// files or per compute unit thread limits, so the estimate only accounts
+// for local memory and does not depend on the work-group size; PrivateMem
+// is reported for information. GroupsPerComputeUnit is zero when the kernel
+// uses no local memory and local memory does not limit residency.
+type ResourceReport struct {
+	Kernel string
+	Device string
+
+	MaxGroupSize      int // largest work-group the kernel can be launched with
+	PreferredMultiple int
+
+	StaticLocalMem  int64 // local memory per group declared by the kernel
+	DynamicLocalMem int64 // local memory per group of the LocalBuffer arguments
+	LocalMem        int64 // total local memory per group
+	PrivateMem      int64 // private memory per work-item
+	DeviceLocalMem  int64
+	ComputeUnits    int
+
+	GroupsPerComputeUnit int
+	ResidentGroups       int
+}
+
+//////////////// Basic Functions ////////////////
+
+// Fills in the local memory totals and the residency estimate from the size
+// the driver reports now and the static size of the kernel.
+func (r *ResourceReport) estimate(reportedLocal, staticLocal int64) {
+	// CL_KERNEL_LOCAL_MEM_SIZE should include the __local arguments set so
+	// far, but not every driver counts them
+	r.StaticLocalMem = staticLocal
+	r.LocalMem = staticLocal + r.DynamicLocalMem
+	if reportedLocal > r.LocalMem {
+		r.LocalMem = reportedLocal
+	}
+	r.GroupsPerComputeUnit, r.ResidentGroups = 0, 0
+	if r.LocalMem > 0 {
+		r.GroupsPerComputeUnit = int(r.DeviceLocalMem / r.LocalMem)
+		r.ResidentGroups = r.GroupsPerComputeUnit * r.ComputeUnits
+	}
+}
+
+// Whether the local memory per group fits on the device.
+func (r *ResourceReport) Fits() bool {
+	return r.LocalMem <= r.DeviceLocalMem
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20 && n%(1<<20) == 0:
+		return strconv.FormatInt(n>>20, 10) + " MiB"
+	case n >= 1<<10 && n%(1<<10) == 0:
+		return strconv.FormatInt(n>>10, 10) + " KiB"
+	}
+	return strconv.FormatInt(n, 10) + " B"
+}
+
+func (r *ResourceReport) String() string {
+	resident := "not limited by local memory"
+	if r.LocalMem > 0 {
+		resident = fmt.Sprintf("%d per compute unit, %d total", r.GroupsPerComputeUnit, r.ResidentGroups)
+	}
+	return fmt.Sprintf("%s on %s: max group %d, multiple %d, local %s of %s, private %s per item, resident groups %s",
+		r.Kernel, r.Device, r.MaxGroupSize, r.PreferredMultiple,
+		formatBytes(r.LocalMem), formatBytes(r.DeviceLocalMem), formatBytes(r.PrivateMem), resident)
+}
+
+// Writes the reports as a table with one row per kernel and device.
+func WriteResourceTable(w io.Writer, reports []*ResourceReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KERNEL\tDEVICE\tMAX GROUP\tMULTIPLE\tSTATIC LOCAL\tDYNAMIC LOCAL\tDEVICE LOCAL\tPRIVATE/ITEM\tGROUPS/CU\tRESIDENT\t")
+	for _, r := range reports {
+		perCU, resident := "-", "-"
+		if r.LocalMem > 0 {
+			perCU, resident = strconv.Itoa(r.GroupsPerComputeUnit), strconv.Itoa(r.ResidentGroups)
+		}
+		if !r.Fits() {
+			perCU, resident = "0 (too much local)", "0"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+			r.Kernel, r.Device, r.MaxGroupSize, r.PreferredMultiple,
+			formatBytes(r.StaticLocalMem), formatBytes(r.DynamicLocalMem), formatBytes(r.DeviceLocalMem),
+			formatBytes(r.PrivateMem), perCU, resident)
+	}
+	return tw.Flush()
+}
+
+//////////////// Abstract Functions ////////////////
+
+// Local memory per work-group of the LocalBuffer arguments set so far.
+func (k *Kernel) DynamicLocalMemSize() int64 {
+	var total int64
+	for _, size := range k.localArgs {
+		total += int64(size)
+	}
+	return total
+}
+
+// Reports the resources the kernel uses on device, with its arguments as
+// currently set, and estimates how many of its work-groups can be resident.
+func (k *Kernel) ResourceReport(device *Device) (*ResourceReport, error) {
+	r := &ResourceReport{
+		Kernel:          k.name,
+		Device:          device.Name(),
+		DynamicLocalMem: k.DynamicLocalMemSize(),
+		DeviceLocalMem:  device.LocalMemSize(),
+		ComputeUnits:    device.MaxComputeUnits(),
+	}
+	var err error
+	if r.MaxGroupSize, err = k.WorkGroupSize(device); err != nil {
+		return nil, err
+	}
+	if r.PreferredMultiple, err = k.PreferredWorkGroupSizeMultiple(device); err != nil {
+		return nil, err
+	}
+	local, err := k.WorkGroupLocalMemSize(device)
+	if err != nil {
+		return nil, err
+	}
+	private, err := k.WorkGroupPrivateMemSize(device)
+	if err != nil {
+		return nil, err
+	}
+	r.PrivateMem = int64(private)
+	r.estimate(int64(local), k.staticLocalMemSize(device, int64(local)))
+	return r, nil
+}
+
+// Checks that the local memory the kernel needs per work-group, including
+// the LocalBuffer arguments set so far, fits on device, so that an oversized
+// launch fails with ErrLocalMemoryExceeded before it is enqueued.
+func (k *Kernel) CheckLocalMemory(device *Device) error {
+	local, err := k.WorkGroupLocalMemSize(device)
+	if err != nil {
+		return err
+	}
+	r := &ResourceReport{DynamicLocalMem: k.DynamicLocalMemSize(), DeviceLocalMem: device.LocalMemSize()}
+	r.estimate(int64(local), k.staticLocalMemSize(device, int64(local)))
+	if !r.Fits() {
+		return ErrLocalMemoryExceeded{Kernel: k.name, Device: device.Name(), Required: r.LocalMem, Available: r.DeviceLocalMem}
+	}
+	return nil
+}
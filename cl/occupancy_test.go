@@ -0,0 +1,68 @@
+package cl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestResourceEstimate(t *testing.T) {
+	r := &ResourceReport{DynamicLocalMem: 4096, DeviceLocalMem: 48 << 10, ComputeUnits: 20}
+	// A driver that leaves the LocalBuffer arguments out of its total
+	r.estimate(2048, 2048)
+	if r.LocalMem != 6144 || r.StaticLocalMem != 2048 || r.GroupsPerComputeUnit != 8 || r.ResidentGroups != 160 {
+		t.Errorf("unexpected estimate %+v", r)
+	}
+	// A driver that includes them
+	r.estimate(6144, 2048)
+	if r.LocalMem != 6144 || r.StaticLocalMem != 2048 {
+		t.Errorf("unexpected estimate %+v", r)
+	}
+	// Static local memory at least as large as the LocalBuffer arguments,
+	// left out of the total
+	r.estimate(8192, 8192)
+	if r.LocalMem != 12288 || r.StaticLocalMem != 8192 {
+		t.Errorf("unexpected estimate %+v", r)
+	}
+
+	r.DynamicLocalMem = 8 << 10
+	r.DeviceLocalMem = 12 << 10
+	r.estimate(8<<10, 8<<10)
+	if r.Fits() || r.GroupsPerComputeUnit != 0 {
+		t.Errorf("expected %+v not to fit", r)
+	}
+	r.DynamicLocalMem = 64 << 10
+	r.estimate(0, 0)
+	if r.Fits() {
+		t.Errorf("expected %+v not to fit", r)
+	}
+}
+
+func TestStaticLocalMemSize(t *testing.T) {
+	device := &Device{}
+	k := &Kernel{}
+	if got := k.staticLocalMemSize(device, 1024); got != 1024 {
+		t.Errorf("without local arguments: got %d, expected the reported size", got)
+	}
+	k.localArgs = map[int]int{0: 512}
+	if got := k.staticLocalMemSize(device, 1536); got != 0 {
+		t.Errorf("with local arguments and nothing recorded: got %d, expected 0", got)
+	}
+}
+
+func TestWriteResourceTable(t *testing.T) {
+	r := &ResourceReport{Kernel: "reduce", Device: "GPU", MaxGroupSize: 1024, PreferredMultiple: 32, DeviceLocalMem: 64 << 10, ComputeUnits: 4}
+	r.estimate(16<<10, 16<<10)
+	var buf bytes.Buffer
+	if err := WriteResourceTable(&buf, []*ResourceReport{r}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected table:\n%s", buf.String())
+	}
+	fields := strings.Fields(lines[1])
+	if fields[0] != "reduce" || fields[4] != "16" || fields[5] != "KiB" || fields[len(fields)-2] != "4" || fields[len(fields)-1] != "16" {
+		t.Errorf("unexpected table:\n%s", buf.String())
+	}
+}
@@ -20,3 +20,29 @@
 	#error "This package requires OpenCL 1.2"
 #endif
 
+/*
+  Looks up an OpenCL entry point in the loaded OpenCL library at run time.
+  Functions newer than OpenCL 1.2 are called through it so the package still
+  links and loads against an OpenCL 1.2 library; NULL means it is missing.
+*/
+#ifndef GO2OPENCL_CORE_FUNCTION_ADDRESS
+#define GO2OPENCL_CORE_FUNCTION_ADDRESS
+#ifdef _WIN32
+	#include <windows.h>
+static inline void *CLGetCoreFunctionAddress(const char *name) {
+	HMODULE lib = GetModuleHandleA("OpenCL.dll");
+	return lib == NULL ? NULL : (void *)GetProcAddress(lib, name);
+}
+#else
+	#include <dlfcn.h>
+static inline void *CLGetCoreFunctionAddress(const char *name) {
+	void *self = dlopen(NULL, RTLD_LAZY);
+	void *fn = NULL;
+	if (self != NULL) {
+		fn = dlsym(self, name);
+		dlclose(self);
+	}
+	return fn;
+}
+#endif
+#endif
@@ -0,0 +1,277 @@
+package cl
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+//////////////// Basic Types ////////////////
+
+// An option of the OpenCL C compiler or linker that takes no argument.
+type BuildFlag string
+
+const (
+	// Math intrinsics
+	FlagSinglePrecisionConstant        BuildFlag = "-cl-single-precision-constant"
+	FlagDenormsAreZero                 BuildFlag = "-cl-denorms-are-zero"
+	FlagFP32CorrectlyRoundedDivideSqrt BuildFlag = "-cl-fp32-correctly-rounded-divide-sqrt"
+	// Optimizations
+	FlagOptDisable              BuildFlag = "-cl-opt-disable"
+	FlagMadEnable               BuildFlag = "-cl-mad-enable"
+	FlagNoSignedZeros           BuildFlag = "-cl-no-signed-zeros"
+	FlagUnsafeMathOptimizations BuildFlag = "-cl-unsafe-math-optimizations"
+	FlagFiniteMathOnly          BuildFlag = "-cl-finite-math-only"
+	FlagFastRelaxedMath         BuildFlag = "-cl-fast-relaxed-math"
+	FlagUniformWorkGroupSize    BuildFlag = "-cl-uniform-work-group-size"
+	FlagNoSubgroupIFP           BuildFlag = "-cl-no-subgroup-ifp"
+	// Linker
+	FlagCreateLibrary     BuildFlag = "-create-library"
+	FlagEnableLinkOptions BuildFlag = "-enable-link-options"
+)
+
+// Flags accepted by clLinkProgram when linking an executable.
+var linkFlags = map[BuildFlag]bool{
+	FlagDenormsAreZero:          true,
+	FlagNoSignedZeros:           true,
+	FlagUnsafeMathOptimizations: true,
+	FlagFiniteMathOnly:          true,
+	FlagFastRelaxedMath:         true,
+	FlagNoSubgroupIFP:           true,
+}
+
+// Flags only accepted by clLinkProgram.
+var linkOnlyFlags = map[BuildFlag]bool{
+	FlagCreateLibrary:     true,
+	FlagEnableLinkOptions: true,
+}
+
+type ErrBuildOption struct {
+	Option string
+	Reason string
+}
+
+func (e ErrBuildOption) Error() string {
+	return fmt.Sprintf("cl: invalid build option %q: %s", e.Option, e.Reason)
+}
+
+//////////////// Abstract Types ////////////////
+
+// BuildOptions assembles the option string passed to BuildProgram,
+// CompileProgram and LinkProgram, quoting macro values and include paths as
+// needed. The setters return the options so calls can be chained; an invalid
+// value is reported by the String methods. The zero value is ready to use.
+type BuildOptions struct {
+	defines       []string
+	includeDirs   []string
+	std           string
+	flags         []BuildFlag
+	kernelArgInfo bool
+	noWarnings    bool
+	werror        bool
+	vendor        []string
+	err           error
+}
+
+//////////////// Basic Functions ////////////////
+
+var (
+	macroName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	clStd     = regexp.MustCompile(`^(CL[1-3]\.[0-9]|CLC\+\+(1\.0|2021)?)$`)
+)
+
+func NewBuildOptions() *BuildOptions {
+	return &BuildOptions{}
+}
+
+// Quotes s so that the option parser of the driver reads it back as a
+// single argument.
+func quoteOption(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\r\n\"'\\") {
+		return s
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		if r == '"' || r == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+//////////////// Abstract Functions ////////////////
+
+func (o *BuildOptions) fail(option, reason string) *BuildOptions {
+	if o.err == nil {
+		o.err = ErrBuildOption{Option: option, Reason: reason}
+	}
+	return o
+}
+
+// Defines the preprocessor macro name, with value 1 (-D name).
+func (o *BuildOptions) Define(name string) *BuildOptions {
+	if !macroName.MatchString(name) {
+		return o.fail("-D "+name, "macro name is not an identifier")
+	}
+	o.defines = append(o.defines, name)
+	return o
+}
+
+// Defines the preprocessor macro name as value (-D name=value).
+func (o *BuildOptions) DefineValue(name, value string) *BuildOptions {
+	if !macroName.MatchString(name) {
+		return o.fail("-D "+name, "macro name is not an identifier")
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return o.fail("-D "+name, "macro value contains a line break")
+	}
+	o.defines = append(o.defines, name+"="+value)
+	return o
+}
+
+// Adds dir to the directories searched for header files (-I dir).
+func (o *BuildOptions) IncludeDir(dir string) *BuildOptions {
+	if dir == "" {
+		return o.fail("-I", "empty include directory")
+	}
+	o.includeDirs = append(o.includeDirs, dir)
+	return o
+}
+
+// Selects the OpenCL C version to compile for, e.g. "CL1.2" or "CL2.0"
+// (-cl-std=version).
+func (o *BuildOptions) Std(version string) *BuildOptions {
+	if !clStd.MatchString(version) {
+		return o.fail("-cl-std="+version, "unknown language version")
+	}
+	o.std = version
+	return o
+}
+
+// Adds flags such as FlagFastRelaxedMath or FlagCreateLibrary.
+func (o *BuildOptions) Flags(flags ...BuildFlag) *BuildOptions {
+	for _, f := range flags {
+		if !strings.HasPrefix(string(f), "-") {
+			return o.fail(string(f), "flags must start with '-'")
+		}
+		o.flags = append(o.flags, f)
+	}
+	return o
+}
+
+// Asks the compiler to keep the kernel argument information needed by
+// Kernel.ArgName and friends (-cl-kernel-arg-info).
+func (o *BuildOptions) KernelArgInfo() *BuildOptions {
+	o.kernelArgInfo = true
+	return o
+}
+
+// Suppresses all warnings (-w).
+func (o *BuildOptions) NoWarnings() *BuildOptions {
+	o.noWarnings = true
+	return o
+}
+
+// Turns warnings into errors (-Werror).
+func (o *BuildOptions) WarningsAsErrors() *BuildOptions {
+	o.werror = true
+	return o
+}
+
+// Appends vendor specific options verbatim, e.g. "-nv-verbose". They are
+// neither quoted nor validated.
+func (o *BuildOptions) Vendor(options ...string) *BuildOptions {
+	o.vendor = append(o.vendor, options...)
+	return o
+}
+
+// Whether any option only understood by the compiler is set.
+func (o *BuildOptions) hasCompileOptions() (string, bool) {
+	switch {
+	case len(o.defines) > 0:
+		return "-D", true
+	case len(o.includeDirs) > 0:
+		return "-I", true
+	case o.std != "":
+		return "-cl-std", true
+	case o.kernelArgInfo:
+		return "-cl-kernel-arg-info", true
+	case o.noWarnings:
+		return "-w", true
+	case o.werror:
+		return "-Werror", true
+	}
+	for _, f := range o.flags {
+		if !linkFlags[f] && !linkOnlyFlags[f] {
+			return string(f), true
+		}
+	}
+	return "", false
+}
+
+func (o *BuildOptions) render() string {
+	var parts []string
+	for _, d := range o.defines {
+		parts = append(parts, "-D", quoteOption(d))
+	}
+	for _, dir := range o.includeDirs {
+		parts = append(parts, "-I", quoteOption(dir))
+	}
+	if o.std != "" {
+		parts = append(parts, "-cl-std="+o.std)
+	}
+	if o.kernelArgInfo {
+		parts = append(parts, "-cl-kernel-arg-info")
+	}
+	if o.noWarnings {
+		parts = append(parts, "-w")
+	}
+	if o.werror {
+		parts = append(parts, "-Werror")
+	}
+	for _, f := range o.flags {
+		parts = append(parts, string(f))
+	}
+	parts = append(parts, o.vendor...)
+	return strings.Join(parts, " ")
+}
+
+// Renders the options for BuildProgram, which compiles and links in one
+// step. Linker only flags such as FlagCreateLibrary are rejected.
+func (o *BuildOptions) BuildString() (string, error) {
+	if o.err != nil {
+		return "", o.err
+	}
+	for _, f := range o.flags {
+		if linkOnlyFlags[f] {
+			return "", ErrBuildOption{Option: string(f), Reason: "only valid for LinkProgram"}
+		}
+	}
+	return o.render(), nil
+}
+
+// Renders the options for CompileProgram. Linker flags are rejected.
+func (o *BuildOptions) CompileString() (string, error) {
+	return o.BuildString()
+}
+
+// Renders the options for LinkProgram. Preprocessor, language and warning
+// options as well as compile-only flags are rejected.
+func (o *BuildOptions) LinkString() (string, error) {
+	if o.err != nil {
+		return "", o.err
+	}
+	if opt, ok := o.hasCompileOptions(); ok {
+		return "", ErrBuildOption{Option: opt, Reason: "only valid when compiling"}
+	}
+	return o.render(), nil
+}
+
+// Renders the options for BuildProgram, or an empty string if they are invalid.
+func (o *BuildOptions) String() string {
+	s, _ := o.BuildString()
+	return s
+}
@@ -0,0 +1,40 @@
+package cl
+
+import "testing"
+
+func TestBuildOptions(t *testing.T) {
+	opts := NewBuildOptions().
+		Define("USE_FMA").
+		DefineValue("GREETING", `"hello world"`).
+		IncludeDir("/opt/my kernels").
+		Std("CL2.0").
+		KernelArgInfo().
+		WarningsAsErrors().
+		Flags(FlagFastRelaxedMath, FlagMadEnable).
+		Vendor("-nv-verbose")
+	s, err := opts.BuildString()
+	if err != nil {
+		t.Fatalf("BuildString failed: %+v", err)
+	}
+	want := `-D USE_FMA -D "GREETING=\"hello world\"" -I "/opt/my kernels" -cl-std=CL2.0 -cl-kernel-arg-info -Werror -cl-fast-relaxed-math -cl-mad-enable -nv-verbose`
+	if s != want {
+		t.Errorf("unexpected options:\n got %s\nwant %s", s, want)
+	}
+	if _, err := opts.LinkString(); err == nil {
+		t.Errorf("expected LinkString to reject compile options")
+	}
+
+	link, err := NewBuildOptions().Flags(FlagCreateLibrary, FlagFastRelaxedMath).LinkString()
+	if err != nil || link != "-create-library -cl-fast-relaxed-math" {
+		t.Errorf("unexpected link options %q: %v", link, err)
+	}
+	if _, err := NewBuildOptions().Flags(FlagCreateLibrary).CompileString(); err == nil {
+		t.Errorf("expected CompileString to reject -create-library")
+	}
+	if _, err := NewBuildOptions().Define("1BAD").BuildString(); err == nil {
+		t.Errorf("expected an error for an invalid macro name")
+	}
+	if _, err := NewBuildOptions().Std("CL9").BuildString(); err == nil {
+		t.Errorf("expected an error for an unknown language version")
+	}
+}
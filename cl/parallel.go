@@ -0,0 +1,151 @@
+package cl
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+//////////////// Abstract Types ////////////////
+
+// The outcome of building sources for one device.
+type DeviceBuild struct {
+	Device  *Device
+	Program *Program
+	Err     error
+}
+
+// The programs built by BuildProgramParallel, one per device, in the order
+// of the devices passed to it.
+type ParallelBuild struct {
+	Builds []DeviceBuild
+}
+
+// Returned by BuildProgramParallel when the build failed on some devices.
+// Failures holds the outcome of each failed device, usually with a
+// BuildError.
+type ParallelBuildError struct {
+	Failures []DeviceBuild
+}
+
+func (e ParallelBuildError) Error() string {
+	msgs := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		name := "unknown device"
+		if f.Device != nil && f.Device.id != nil {
+			name = f.Device.Name()
+		}
+		msgs[i] = fmt.Sprintf("%s: %v", name, f.Err)
+	}
+	return fmt.Sprintf("cl: build failed on %d device(s): %s", len(e.Failures), strings.Join(msgs, "; "))
+}
+
+// The errors of the failed devices, for errors.Is and errors.As.
+func (e ParallelBuildError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f.Err
+	}
+	return errs
+}
+
+//////////////// Abstract Functions ////////////////
+
+// Builds sources with options for each of devices (all devices of the
+// context if nil) concurrently. Each device gets its own program, since a
+// single program cannot be built for several devices at once. The returned
+// ParallelBuild holds the outcome of every device; the error is a
+// ParallelBuildError if any device failed, and the ParallelBuild is still
+// returned so callers can carry on with the devices that succeeded.
+func (ctx *Context) BuildProgramParallel(sources []string, devices []*Device, options string) (*ParallelBuild, error) {
+	if len(devices) == 0 {
+		devices = ctx.devices
+	}
+	pb := &ParallelBuild{Builds: make([]DeviceBuild, len(devices))}
+	var wg sync.WaitGroup
+	for i, dev := range devices {
+		wg.Add(1)
+		go func(b *DeviceBuild, dev *Device) {
+			defer wg.Done()
+			b.Device = dev
+			program, err := ctx.CreateProgramWithSource(sources)
+			if err != nil {
+				b.Err = err
+				return
+			}
+			if err := program.BuildProgram([]*Device{dev}, options); err != nil {
+				program.Release()
+				b.Err = err
+				return
+			}
+			b.Program = program
+		}(&pb.Builds[i], dev)
+	}
+	wg.Wait()
+	if failed := pb.Failed(); len(failed) > 0 {
+		return pb, ParallelBuildError{Failures: failed}
+	}
+	return pb, nil
+}
+
+// Devices the build succeeded on.
+func (pb *ParallelBuild) Devices() []*Device {
+	var devices []*Device
+	for _, b := range pb.Builds {
+		if b.Err == nil {
+			devices = append(devices, b.Device)
+		}
+	}
+	return devices
+}
+
+// Outcomes of the devices the build failed on.
+func (pb *ParallelBuild) Failed() []DeviceBuild {
+	var failed []DeviceBuild
+	for _, b := range pb.Builds {
+		if b.Err != nil {
+			failed = append(failed, b)
+		}
+	}
+	return failed
+}
+
+// The program built for device, or nil if its build failed or it was not
+// one of the devices built for.
+func (pb *ParallelBuild) Program(device *Device) *Program {
+	for _, b := range pb.Builds {
+		if b.Device == device || device != nil && device.id != nil && b.Device != nil && b.Device.id == device.id {
+			return b.Program
+		}
+	}
+	return nil
+}
+
+// Creates the kernel called name in the program of every device the build
+// succeeded on, keyed by device.
+func (pb *ParallelBuild) CreateKernel(name string) (map[*Device]*Kernel, error) {
+	kernels := make(map[*Device]*Kernel)
+	for _, b := range pb.Builds {
+		if b.Err != nil {
+			continue
+		}
+		k, err := b.Program.CreateKernel(name)
+		if err != nil {
+			for _, k := range kernels {
+				k.Release()
+			}
+			return nil, err
+		}
+		kernels[b.Device] = k
+	}
+	return kernels, nil
+}
+
+// Releases the programs that were built.
+func (pb *ParallelBuild) Release() {
+	for _, b := range pb.Builds {
+		if b.Program != nil {
+			b.Program.Release()
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package cl
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParallelBuild(t *testing.T) {
+	ok1, ok2, bad1, bad2 := &Device{}, &Device{}, &Device{}, &Device{}
+	p1, p2 := &Program{}, &Program{}
+	compileErr := BuildError{Message: "kernel.cl:1:1: error: expected identifier"}
+	pb := &ParallelBuild{Builds: []DeviceBuild{
+		{Device: ok1, Program: p1},
+		{Device: bad1, Err: compileErr},
+		{Device: ok2, Program: p2},
+		{Device: bad2, Err: ErrOutOfHostMemory},
+	}}
+
+	devices := pb.Devices()
+	if len(devices) != 2 || devices[0] != ok1 || devices[1] != ok2 {
+		t.Errorf("Devices: got %v", devices)
+	}
+	failed := pb.Failed()
+	if len(failed) != 2 || failed[0].Device != bad1 || failed[1].Device != bad2 {
+		t.Errorf("Failed: got %+v", failed)
+	}
+	programs := []struct {
+		device *Device
+		want   *Program
+	}{
+		{ok1, p1},
+		{ok2, p2},
+		{bad1, nil},
+		{&Device{}, nil},
+	}
+	for i, c := range programs {
+		if got := pb.Program(c.device); got != c.want {
+			t.Errorf("Program %d: got %p, expected %p", i, got, c.want)
+		}
+	}
+	if failed := (&ParallelBuild{Builds: []DeviceBuild{{Device: ok1, Program: p1}}}).Failed(); failed != nil {
+		t.Errorf("Failed without failures: got %+v", failed)
+	}
+}
+
+func TestParallelBuildError(t *testing.T) {
+	first := BuildError{Message: "first log"}
+	second := BuildError{Message: "second log"}
+	err := error(ParallelBuildError{Failures: []DeviceBuild{
+		{Device: &Device{}, Err: first},
+		{Err: second},
+		{Err: ErrOutOfHostMemory},
+	}})
+
+	msg := err.Error()
+	for _, want := range []string{"3 device(s)", "first log", "second log", ErrOutOfHostMemory.Error()} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message %q lacks %q", msg, want)
+		}
+	}
+	cases := []struct {
+		target error
+		is     bool
+	}{
+		{first, true},
+		{second, true},
+		{ErrOutOfHostMemory, true},
+		{ErrBuildProgramFailure, false},
+	}
+	for _, c := range cases {
+		if errors.Is(err, c.target) != c.is {
+			t.Errorf("errors.Is(%v): expected %v", c.target, c.is)
+		}
+	}
+	var buildErr BuildError
+	if !errors.As(err, &buildErr) || buildErr.Message != "first log" {
+		t.Errorf("errors.As: got %+v, expected the first BuildError", buildErr)
+	}
+	if errs := err.(ParallelBuildError).Unwrap(); len(errs) != 3 || errs[1] != second {
+		t.Errorf("Unwrap: got %v", errs)
+	}
+}
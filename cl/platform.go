@@ -31,6 +31,15 @@ func GetPlatforms() ([]*Platform, error) {
 	return platforms, nil
 }
 
+// Whether the loaded OpenCL library exports the function name. Entry points
+// added after OpenCL 1.2 are missing from older libraries even when a
+// platform reports a newer version.
+func hasCoreFunction(name string) bool {
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	return C.CLGetCoreFunctionAddress(cName) != nil
+}
+
 //////////////// Abstract Functions ////////////////
 func (p *Platform) GetDevices(deviceType DeviceType) ([]*Device, error) {
 	return GetDevices(p, deviceType)
@@ -77,6 +86,18 @@ func (p *Platform) Version() string {
 	}
 }
 
+// Major and minor OpenCL version supported by the platform, parsed from
+// Version. Both are zero if the version string is malformed.
+func (p *Platform) VersionNumber() (major, minor int) {
+	return parseOpenCLVersion(p.Version())
+}
+
+// Whether the platform supports at least OpenCL major.minor.
+func (p *Platform) HasVersion(major, minor int) bool {
+	platMajor, platMinor := p.VersionNumber()
+	return platMajor > major || platMajor == major && platMinor >= minor
+}
+
 func (p *Platform) Extensions() string {
 	if str, err := p.getInfoString(C.CL_PLATFORM_EXTENSIONS); err != nil {
 		panic("Platform.Extensions() should never fail")
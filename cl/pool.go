@@ -0,0 +1,163 @@
+package cl
+
+/*
+#include "./opencl.h"
+
+typedef cl_kernel (CL_API_CALL *clCloneKernel_go_fn)(cl_kernel, cl_int *);
+
+static cl_kernel CLCloneKernel(cl_kernel source_kernel, cl_int *errcode_ret) {
+	clCloneKernel_go_fn fn = (clCloneKernel_go_fn)CLGetCoreFunctionAddress("clCloneKernel");
+	if (fn == NULL) {
+		*errcode_ret = CL_INVALID_OPERATION;
+		return NULL;
+	}
+	return fn(source_kernel, errcode_ret);
+}
+*/
+import "C"
+
+import (
+	"runtime"
+	"sync"
+)
+
+//////////////// Abstract Types ////////////////
+
+// A set of interchangeable kernel objects for one kernel function of a
+// program, so that goroutines sharing the program can each set arguments
+// and launch without racing on the argument state of a single cl_kernel.
+// Take a kernel with Get and return it with Put, or use Enqueue.
+type KernelPool struct {
+	program *Program
+	name    string
+	clone   bool
+
+	mu       sync.Mutex
+	proto    *Kernel
+	free     []*Kernel
+	released bool
+}
+
+//////////////// Abstract Functions ////////////////
+
+// Returns a copy of the kernel with the same arguments set, using
+// clCloneKernel. Requires a platform and OpenCL library supporting OpenCL
+// 2.1.
+func (k *Kernel) Clone() (*Kernel, error) {
+	program, err := k.Program()
+	if err != nil {
+		return nil, err
+	}
+	if err := program.requireFunction("clCloneKernel", 2, 1); err != nil {
+		return nil, err
+	}
+	var errCode C.cl_int
+	clKernel := C.CLCloneKernel(k.clKernel, &errCode)
+	if errCode != C.CL_SUCCESS {
+		return nil, toError(errCode)
+	}
+	clone := k.copyState()
+	clone.clKernel = clKernel
+	runtime.SetFinalizer(clone, releaseKernel)
+	return clone, nil
+}
+
+// A kernel without a cl_kernel that shares the argument metadata of k and
+// has its own copy of the local argument sizes.
+func (k *Kernel) copyState() *Kernel {
+	clone := &Kernel{name: k.name, argInfo: k.argInfo, argIndex: k.argIndex, staticLocal: k.staticLocal}
+	for index, size := range k.localArgs {
+		if clone.localArgs == nil {
+			clone.localArgs = make(map[int]int, len(k.localArgs))
+		}
+		clone.localArgs[index] = size
+	}
+	return clone
+}
+
+// Creates a pool of kernels for the kernel function called name. New
+// kernels are cloned when the platform of the program and the OpenCL
+// library support OpenCL 2.1 and created from the program otherwise. The pool holds a reference to the
+// program until it is released.
+func (p *Program) NewKernelPool(name string) (*KernelPool, error) {
+	proto, err := p.CreateKernel(name)
+	if err != nil {
+		return nil, err
+	}
+	clone := p.requireFunction("clCloneKernel", 2, 1) == nil
+	return &KernelPool{program: p.retained(), name: name, clone: clone, proto: proto}, nil
+}
+
+// Takes a kernel out of the pool, creating one if none is free. Its
+// arguments are those set by its previous user, if any.
+func (kp *KernelPool) Get() (*Kernel, error) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	if kp.released {
+		return nil, ErrInvalidKernel
+	}
+	if n := len(kp.free); n > 0 {
+		k := kp.free[n-1]
+		kp.free = kp.free[:n-1]
+		return k, nil
+	}
+	if kp.clone {
+		return kp.proto.Clone()
+	}
+	return kp.program.CreateKernel(kp.name)
+}
+
+// Returns a kernel taken with Get to the pool. Kernels returned after the
+// pool was released are released.
+func (kp *KernelPool) Put(k *Kernel) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	if kp.released {
+		k.Release()
+		return
+	}
+	kp.free = append(kp.free, k)
+}
+
+// Sets args on a kernel of the pool and enqueues it, as
+// CommandQueue.EnqueueKernelArgs does, then returns the kernel to the pool.
+// Safe for concurrent use.
+func (kp *KernelPool) Enqueue(q *CommandQueue, args []interface{}, globalWorkOffset, globalWorkSize, localWorkSize []int, eventWaitList []*Event) (*Event, error) {
+	k, err := kp.Get()
+	if err != nil {
+		return nil, err
+	}
+	defer kp.Put(k)
+	return q.EnqueueKernelArgs(k, args, globalWorkOffset, globalWorkSize, localWorkSize, eventWaitList)
+}
+
+// Releases the free kernels and the reference to the program. Kernels
+// still in use are released when they are put back.
+func (kp *KernelPool) Release() {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	if kp.released {
+		return
+	}
+	kp.released = true
+	for _, k := range kp.free {
+		k.Release()
+	}
+	kp.free = nil
+	kp.proto.Release()
+	kp.program.Release()
+}
+
+// Sets args on the kernel and enqueues it as one step, holding the kernel
+// so that goroutines sharing it through EnqueueKernelArgs cannot interleave
+// their arguments. SetArg and SetArgByName do not hold it. The
+// arguments are captured when the launch is enqueued, so the kernel is free
+// again once this returns.
+func (q *CommandQueue) EnqueueKernelArgs(kernel *Kernel, args []interface{}, globalWorkOffset, globalWorkSize, localWorkSize []int, eventWaitList []*Event) (*Event, error) {
+	kernel.mu.Lock()
+	defer kernel.mu.Unlock()
+	if err := kernel.SetArgs(args...); err != nil {
+		return nil, err
+	}
+	return q.EnqueueNDRangeKernel(kernel, globalWorkOffset, globalWorkSize, localWorkSize, eventWaitList)
+}
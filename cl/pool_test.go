@@ -0,0 +1,63 @@
+package cl
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestKernelPoolLifecycle(t *testing.T) {
+	proto := &Kernel{name: "scale"}
+	kp := &KernelPool{program: &Program{}, name: "scale", proto: proto}
+
+	a, b := &Kernel{name: "scale"}, &Kernel{name: "scale"}
+	kp.Put(a)
+	kp.Put(b)
+	if k, err := kp.Get(); err != nil || k != b {
+		t.Fatalf("Get: got %p, %v, expected the last kernel put back", k, err)
+	}
+	if k, err := kp.Get(); err != nil || k != a {
+		t.Fatalf("Get: got %p, %v, expected the first kernel put back", k, err)
+	}
+	kp.Put(a)
+
+	kp.Release()
+	if !kp.released || kp.free != nil {
+		t.Errorf("Release: free kernels not dropped")
+	}
+	kp.Release()
+	if _, err := kp.Get(); !errors.Is(err, ErrInvalidKernel) {
+		t.Errorf("Get after Release: got %v, expected ErrInvalidKernel", err)
+	}
+	kp.Put(b)
+	if len(kp.free) != 0 {
+		t.Errorf("Put after Release: kernel kept in the pool")
+	}
+}
+
+func TestKernelCopyState(t *testing.T) {
+	k := &Kernel{name: "reduce", argIndex: map[string]int{"scratch": 1}, localArgs: map[int]int{1: 256}}
+	clone := k.copyState()
+	if clone.name != k.name || clone.argIndex["scratch"] != 1 || clone.localArgs[1] != 256 {
+		t.Fatalf("copyState: got %+v", clone)
+	}
+	clone.localArgs[1] = 512
+	clone.localArgs[2] = 64
+	if k.localArgs[1] != 256 || len(k.localArgs) != 1 {
+		t.Errorf("copyState: local arguments of the clone shared with the original: %v", k.localArgs)
+	}
+	if (&Kernel{}).copyState().localArgs != nil {
+		t.Errorf("copyState: local arguments allocated for a kernel without any")
+	}
+}
+
+func TestEnqueueKernelArgsUnlocksOnError(t *testing.T) {
+	k := &Kernel{name: "scale", argInfo: []kernelArgInfo{{address: "Private", name: "factor", typeName: "float"}}}
+	q := &CommandQueue{}
+	for i := 0; i < 2; i++ {
+		_, err := q.EnqueueKernelArgs(k, []interface{}{int32(2)}, nil, []int{64}, nil, nil)
+		var mismatch ErrArgumentMismatch
+		if !errors.As(err, &mismatch) {
+			t.Fatalf("EnqueueKernelArgs: got %v, expected ErrArgumentMismatch", err)
+		}
+	}
+}
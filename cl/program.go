@@ -46,6 +46,50 @@ static cl_program CLLinkProgram(                           cl_context
 							cl_int * err_ret) {
         return clLinkProgram(context, num_devices, devices, build_options, num_programs, in_programs, c_link_program_notify, user_data, err_ret);
 }
+
+typedef cl_program (CL_API_CALL *clCreateProgramWithIL_go_fn)(cl_context, const void *, size_t, cl_int *);
+typedef clCreateProgramWithIL_go_fn clCreateProgramWithILKHR_go_fn;
+
+static cl_program CLCreateProgramWithIL(cl_context context, const void *il, size_t length, cl_int *errcode_ret) {
+        clCreateProgramWithIL_go_fn fn = (clCreateProgramWithIL_go_fn)CLGetCoreFunctionAddress("clCreateProgramWithIL");
+        if (fn == NULL) {
+                *errcode_ret = CL_INVALID_OPERATION;
+                return NULL;
+        }
+        return fn(context, il, length, errcode_ret);
+}
+
+static cl_program CLCreateProgramWithILKHR(cl_platform_id platform, cl_context context, const void *il, size_t length, cl_int *errcode_ret) {
+        clCreateProgramWithILKHR_go_fn fn = (clCreateProgramWithILKHR_go_fn)clGetExtensionFunctionAddressForPlatform(platform, "clCreateProgramWithILKHR");
+        if (fn == NULL) {
+                *errcode_ret = CL_INVALID_OPERATION;
+                return NULL;
+        }
+        return fn(context, il, length, errcode_ret);
+}
+
+#ifndef CL_PROGRAM_IL
+#define CL_PROGRAM_IL 0x1169
+#endif
+#ifndef CL_PROGRAM_SCOPE_GLOBAL_CTORS_PRESENT
+#define CL_PROGRAM_SCOPE_GLOBAL_CTORS_PRESENT 0x116A
+#endif
+#ifndef CL_PROGRAM_SCOPE_GLOBAL_DTORS_PRESENT
+#define CL_PROGRAM_SCOPE_GLOBAL_DTORS_PRESENT 0x116B
+#endif
+#ifndef CL_PROGRAM_BUILD_GLOBAL_VARIABLE_TOTAL_SIZE
+#define CL_PROGRAM_BUILD_GLOBAL_VARIABLE_TOTAL_SIZE 0x1185
+#endif
+
+typedef cl_int (CL_API_CALL *clSetProgramSpecializationConstant_go_fn)(cl_program, cl_uint, size_t, const void *);
+
+static cl_int CLSetProgramSpecializationConstant(cl_program program, cl_uint spec_id, size_t spec_size, const void *spec_value) {
+        clSetProgramSpecializationConstant_go_fn fn = (clSetProgramSpecializationConstant_go_fn)CLGetCoreFunctionAddress("clSetProgramSpecializationConstant");
+        if (fn == NULL) {
+                return CL_INVALID_OPERATION;
+        }
+        return fn(program, spec_id, spec_size, spec_value);
+}
 */
 import "C"
 
@@ -77,9 +121,13 @@ const (
 )
 
 //////////////// Abstract Types ////////////////
+// Returned when building, compiling or linking fails. Message holds the log
+// of the first device that produced one; Result holds the logs and parsed
+// diagnostics of every device.
 type BuildError struct {
 	Message string
 	Device  *Device
+	Result  *BuildResult
 }
 
 func (e BuildError) Error() string {
@@ -90,13 +138,41 @@ func (e BuildError) Error() string {
 	}
 }
 
+// Returned when a feature needs a newer OpenCL version than a device or
+// platform supports, or than the loaded OpenCL library provides, in which
+// case both Device and Platform are nil. It matches ErrUnsupported with
+// errors.Is.
+type ErrVersionRequired struct {
+	Feature  string
+	Major    int
+	Minor    int
+	Device   *Device
+	Platform *Platform
+}
+
+func (e ErrVersionRequired) Error() string {
+	switch {
+	case e.Device != nil:
+		return fmt.Sprintf("cl: %s requires OpenCL %d.%d, not supported by %q (%s)", e.Feature, e.Major, e.Minor, e.Device.Name(), e.Device.Version())
+	case e.Platform != nil:
+		return fmt.Sprintf("cl: %s requires OpenCL %d.%d, not supported by %q (%s)", e.Feature, e.Major, e.Minor, e.Platform.Name(), e.Platform.Version())
+	}
+	return fmt.Sprintf("cl: %s requires OpenCL %d.%d, not provided by the OpenCL library", e.Feature, e.Major, e.Minor)
+}
+
+func (e ErrVersionRequired) Unwrap() error {
+	return ErrUnsupported
+}
+
 type Program struct {
 	clProgram C.cl_program
 	devices   []*Device
 }
 
+// A header program made available to CompileProgram under the name used in
+// #include directives.
 type ProgramHeaders struct {
-        codes   Program
+        codes   *Program
         names   string
 }
 
@@ -152,6 +228,50 @@ func retainProgram(p *Program) {
 	}
 }
 
+// Makes program available to CompileProgram as the header called name.
+func NewProgramHeaders(program *Program, name string) *ProgramHeaders {
+	return &ProgramHeaders{codes: program, names: name}
+}
+
+// Prepends the options BuildProgram always used, OpenCL C 1.2 with kernel
+// argument info, unless options already choose otherwise.
+func defaultBuildOptions(options string) string {
+	var optBuffer bytes.Buffer
+	if !strings.Contains(options, "-cl-std=") {
+		optBuffer.WriteString("-cl-std=CL1.2 ")
+	}
+	if !strings.Contains(options, "-cl-kernel-arg-info") {
+		optBuffer.WriteString("-cl-kernel-arg-info ")
+	}
+	optBuffer.WriteString(options)
+	return optBuffer.String()
+}
+
+func buildHeaderList(headers []*ProgramHeaders) ([]C.cl_program, []*C.char) {
+	programs := make([]C.cl_program, len(headers))
+	names := make([]*C.char, len(headers))
+	for i, h := range headers {
+		programs[i] = h.codes.clProgram
+		names[i] = C.CString(h.names)
+	}
+	return programs, names
+}
+
+func freeHeaderNames(names []*C.char) {
+	for _, n := range names {
+		C.free(unsafe.Pointer(n))
+	}
+}
+
+// Returns a new reference to the program with its own finalizer, so that
+// releasing it leaves p usable.
+func (p *Program) retained() *Program {
+	retainProgram(p)
+	program := &Program{clProgram: p.clProgram, devices: p.devices}
+	runtime.SetFinalizer(program, releaseProgram)
+	return program
+}
+
 //////////////// Abstract Functions ////////////////
 func (p *Program) Release() {
 	releaseProgram(p)
@@ -161,14 +281,17 @@ func (p *Program) Retain() {
 	retainProgram(p)
 }
 
+// Name under which the header can be included.
+func (h *ProgramHeaders) Name() string {
+	return h.names
+}
+
+func (h *ProgramHeaders) Program() *Program {
+	return h.codes
+}
+
 func (p *Program) BuildProgram(devices []*Device, options string) error {
-	var optBuffer bytes.Buffer
-	optBuffer.WriteString("-cl-std=CL1.2 -cl-kernel-arg-info ")
-	var cOptions *C.char
-	if options != "" {
-		optBuffer.WriteString(options)
-	}
-	cOptions = C.CString(optBuffer.String())
+	cOptions := C.CString(defaultBuildOptions(options))
 	defer C.free(unsafe.Pointer(cOptions))
 
 	var deviceList []C.cl_device_id
@@ -179,36 +302,7 @@ func (p *Program) BuildProgram(devices []*Device, options string) error {
 		deviceListPtr = &deviceList[0]
 	}
 	if err := C.clBuildProgram(p.clProgram, numDevices, deviceListPtr, cOptions, nil, nil); err != C.CL_SUCCESS {
-		buffer := make([]byte, 4096)
-		var bLen C.size_t
-		var err C.cl_int
-
-		for _, dev := range p.devices {
-			for i := 2; i >= 0; i-- {
-				err = C.clGetProgramBuildInfo(p.clProgram, dev.id, C.CL_PROGRAM_BUILD_LOG, C.size_t(len(buffer)), unsafe.Pointer(&buffer[0]), &bLen)
-				if err == C.CL_INVALID_VALUE && i > 0 && bLen < 1024*1024 {
-					// INVALID_VALUE probably means our buffer isn't large enough
-					buffer = make([]byte, bLen)
-				} else {
-					break
-				}
-			}
-			if err != C.CL_SUCCESS {
-				return toError(err)
-			}
-
-			if bLen > 1 {
-				return BuildError{
-					Device:  dev,
-					Message: string(buffer[:bLen-1]),
-				}
-			}
-		}
-
-		return BuildError{
-			Device:  nil,
-			Message: "build failed and produced no log entries",
-		}
+		return p.buildError(devices)
 	}
 	return nil
 }
@@ -223,6 +317,7 @@ func (p *Program) CreateKernel(name string) (*Kernel, error) {
 	}
 	kernel := &Kernel{clKernel: clKernel, name: name}
 	runtime.SetFinalizer(kernel, releaseKernel)
+	kernel.recordStaticLocalMem(p)
 	return kernel, nil
 }
 
@@ -272,6 +367,53 @@ func (ctx *Context) CreateProgramWithBuiltInKernels(devices []*Device, kernel_na
         return program, nil
 }
 
+// Creates a program from intermediate language, such as SPIR-V, compiled
+// offline. OpenCL 2.1 platforms use clCreateProgramWithIL, looked up at run
+// time so that older OpenCL libraries still load; otherwise devices with the
+// cl_khr_il_program extension fall back to clCreateProgramWithILKHR. Check
+// Device.ILVersion for the languages a device accepts.
+func (ctx *Context) CreateProgramWithIL(il []byte) (*Program, error) {
+	if len(il) == 0 {
+		return nil, ErrInvalidValue
+	}
+	devices := ctx.devices
+	if len(devices) == 0 {
+		var err error
+		if devices, err = ctx.GetDevices(); err != nil {
+			return nil, err
+		}
+	}
+	if len(devices) == 0 {
+		return nil, ErrInvalidContext
+	}
+	platform := devices[0].Platform()
+	core := platform.HasVersion(2, 1) && hasCoreFunction("clCreateProgramWithIL")
+	ext := true
+	for _, d := range devices {
+		ext = ext && d.HasExtension("cl_khr_il_program")
+	}
+
+	var err C.cl_int
+	var clProgram C.cl_program
+	switch {
+	case core:
+		clProgram = C.CLCreateProgramWithIL(ctx.clContext, unsafe.Pointer(&il[0]), C.size_t(len(il)), &err)
+	case ext:
+		clProgram = C.CLCreateProgramWithILKHR(platform.id, ctx.clContext, unsafe.Pointer(&il[0]), C.size_t(len(il)), &err)
+	default:
+		return nil, ErrUnsupported
+	}
+	if err != C.CL_SUCCESS {
+		return nil, toError(err)
+	}
+	if clProgram == nil {
+		return nil, ErrUnknown
+	}
+	program := &Program{clProgram: clProgram, devices: ctx.devices}
+	runtime.SetFinalizer(program, releaseProgram)
+	return program, nil
+}
+
 func (p *Program) CompileProgram(devices []*Device, options string, program_headers []*ProgramHeaders) error {
 	var cOptions *C.char
         if options != "" {
@@ -286,48 +428,17 @@ func (p *Program) CompileProgram(devices []*Device, options string, program_head
 		deviceListPtr = &deviceList[0]
 	}
 	num_headers := len(program_headers)
-	cHeaders := make([]C.cl_program, num_headers)
-	cHeader_names := make([]*C.char, num_headers)
-	for idx, ph := range program_headers {
-		chs := ph.codes
-		chn := C.CString(ph.names)
-		cHeaders[idx] = chs.clProgram
-		cHeader_names[idx] = chn
-		defer C.free(unsafe.Pointer(&chs))
-		defer C.free(unsafe.Pointer(&chn))
-	}
-	err := C.clCompileProgram(p.clProgram, numDevices, deviceListPtr, cOptions, C.cl_uint(num_headers), &cHeaders[0], &cHeader_names[0], nil, nil)
+	var cHeadersPtr *C.cl_program
+	var cHeader_namesPtr **C.char
+	if num_headers > 0 {
+		cHeaders, cHeader_names := buildHeaderList(program_headers)
+		defer freeHeaderNames(cHeader_names)
+		cHeadersPtr = &cHeaders[0]
+		cHeader_namesPtr = &cHeader_names[0]
+	}
+	err := C.clCompileProgram(p.clProgram, numDevices, deviceListPtr, cOptions, C.cl_uint(num_headers), cHeadersPtr, cHeader_namesPtr, nil, nil)
 	if err != C.CL_SUCCESS {
-		buffer := make([]byte, 4096)
-		var bLen C.size_t
-		var err C.cl_int
-
-		for _, dev := range p.devices {
-			for i := 2; i >= 0; i-- {
-				err = C.clGetProgramBuildInfo(p.clProgram, dev.id, C.CL_PROGRAM_BUILD_LOG, C.size_t(len(buffer)), unsafe.Pointer(&buffer[0]), &bLen)
-				if err == C.CL_INVALID_VALUE && i > 0 && bLen < 1024*1024 {
-					// INVALID_VALUE probably means our buffer isn't large enough
-					buffer = make([]byte, bLen)
-				} else {
-					break
-				}
-			}
-			if err != C.CL_SUCCESS {
-				return toError(err)
-			}
-
-			if bLen > 1 {
-				return BuildError{
-					Device:  dev,
-					Message: string(buffer[:bLen-1]),
-				}
-			}
-		}
-
-		return BuildError{
-			Device:  nil,
-			Message: "build failed and produced no log entries",
-		}
+		return p.buildError(devices)
 	}
 	return nil
 }
@@ -346,48 +457,17 @@ func (p *Program) CompileProgramWithCallback(devices []*Device, options string,
 		deviceListPtr = &deviceList[0]
 	}
 	num_headers := len(program_headers)
-	cHeaders := make([]C.cl_program, num_headers)
-	cHeader_names := make([]*C.char, num_headers)
-	for idx, ph := range program_headers {
-		chs := ph.codes
-		chn := C.CString(ph.names)
-		cHeaders[idx] = chs.clProgram
-		cHeader_names[idx] = chn
-		defer C.free(unsafe.Pointer(&chs))
-		defer C.free(unsafe.Pointer(&chn))
-	}
-	err := C.CLCompileProgram(p.clProgram, numDevices, deviceListPtr, cOptions, C.cl_uint(num_headers), &cHeaders[0], &cHeader_names[0], user_data)
+	var cHeadersPtr *C.cl_program
+	var cHeader_namesPtr **C.char
+	if num_headers > 0 {
+		cHeaders, cHeader_names := buildHeaderList(program_headers)
+		defer freeHeaderNames(cHeader_names)
+		cHeadersPtr = &cHeaders[0]
+		cHeader_namesPtr = &cHeader_names[0]
+	}
+	err := C.CLCompileProgram(p.clProgram, numDevices, deviceListPtr, cOptions, C.cl_uint(num_headers), cHeadersPtr, cHeader_namesPtr, user_data)
 	if err != C.CL_SUCCESS {
-		buffer := make([]byte, 4096)
-		var bLen C.size_t
-		var err C.cl_int
-
-		for _, dev := range p.devices {
-			for i := 2; i >= 0; i-- {
-				err = C.clGetProgramBuildInfo(p.clProgram, dev.id, C.CL_PROGRAM_BUILD_LOG, C.size_t(len(buffer)), unsafe.Pointer(&buffer[0]), &bLen)
-				if err == C.CL_INVALID_VALUE && i > 0 && bLen < 1024*1024 {
-					// INVALID_VALUE probably means our buffer isn't large enough
-					buffer = make([]byte, bLen)
-				} else {
-					break
-				}
-			}
-			if err != C.CL_SUCCESS {
-				return toError(err)
-			}
-
-			if bLen > 1 {
-				return BuildError{
-					Device:  dev,
-					Message: string(buffer[:bLen-1]),
-				}
-			}
-		}
-
-		return BuildError{
-			Device:  nil,
-			Message: "build failed and produced no log entries",
-		}
+		return p.buildError(devices)
 	}
 	return nil
 }
@@ -413,37 +493,14 @@ func (ctx *Context) LinkProgram(programs []*Program, devices []*Device, options
 	programExe := C.clLinkProgram(ctx.clContext, numDevices, deviceListPtr, cOptions, C.cl_uint(len(programs)), &programList[0], nil, nil, &err)
 	p := &Program{clProgram: programExe, devices: devices}
 	if err != C.CL_SUCCESS {
-		buffer := make([]byte, 4096)
-		var bLen C.size_t
-		var err C.cl_int
-
-		for _, dev := range p.devices {
-			for i := 2; i >= 0; i-- {
-				err = C.clGetProgramBuildInfo(p.clProgram, dev.id, C.CL_PROGRAM_BUILD_LOG, C.size_t(len(buffer)), unsafe.Pointer(&buffer[0]), &bLen)
-				if err == C.CL_INVALID_VALUE && i > 0 && bLen < 1024*1024 {
-					// INVALID_VALUE probably means our buffer isn't large enough
-					buffer = make([]byte, bLen)
-				} else {
-					break
-				}
-			}
-			if err != C.CL_SUCCESS {
-				return nil, toError(err)
-			}
-
-			if bLen > 1 {
-				return nil, BuildError{
-					Device:  dev,
-					Message: string(buffer[:bLen-1]),
-				}
-			}
-		}
-
-		return nil, BuildError{
-			Device:  nil,
-			Message: "build failed and produced no log entries",
+		if p.clProgram == nil {
+			return nil, toError(err)
 		}
+		buildErr := p.buildError(devices)
+		releaseProgram(p)
+		return nil, buildErr
 	}
+	runtime.SetFinalizer(p, releaseProgram)
 	return p, nil
 }
 
@@ -468,37 +525,14 @@ func (ctx *Context) LinkProgramWithCallback(programs []*Program, devices []*Devi
 	programExe := C.CLLinkProgram(ctx.clContext, numDevices, deviceListPtr, cOptions, C.cl_uint(len(programs)), &programList[0], user_data, &err)
 	p := &Program{clProgram: programExe, devices: devices}
 	if err != C.CL_SUCCESS {
-		buffer := make([]byte, 4096)
-		var bLen C.size_t
-		var err C.cl_int
-
-		for _, dev := range p.devices {
-			for i := 2; i >= 0; i-- {
-				err = C.clGetProgramBuildInfo(p.clProgram, dev.id, C.CL_PROGRAM_BUILD_LOG, C.size_t(len(buffer)), unsafe.Pointer(&buffer[0]), &bLen)
-				if err == C.CL_INVALID_VALUE && i > 0 && bLen < 1024*1024 {
-					// INVALID_VALUE probably means our buffer isn't large enough
-					buffer = make([]byte, bLen)
-				} else {
-					break
-				}
-			}
-			if err != C.CL_SUCCESS {
-				return nil, toError(err)
-			}
-
-			if bLen > 1 {
-				return nil, BuildError{
-					Device:  dev,
-					Message: string(buffer[:bLen-1]),
-				}
-			}
-		}
-
-		return nil, BuildError{
-			Device:  nil,
-			Message: "build failed and produced no log entries",
+		if p.clProgram == nil {
+			return nil, toError(err)
 		}
+		buildErr := p.buildError(devices)
+		releaseProgram(p)
+		return nil, buildErr
 	}
+	runtime.SetFinalizer(p, releaseProgram)
 	return p, nil
 }
 
@@ -522,16 +556,71 @@ func (p *Program) GetBuildOptions(device *Device) (string, error) {
 }
 
 func (p *Program) GetBuildLog(device *Device) (string, error) {
-        var strC [1024]C.char
-        var strN C.size_t
-        if err := C.clGetProgramBuildInfo(p.clProgram, device.id, C.CL_PROGRAM_BUILD_LOG, 1024, unsafe.Pointer(&strC), &strN); err != C.CL_SUCCESS {
-                panic("Should never fail")
-                return "", toError(err)
-        }
+	var size C.size_t
+	if err := C.clGetProgramBuildInfo(p.clProgram, device.nullableId(), C.CL_PROGRAM_BUILD_LOG, 0, nil, &size); err != C.CL_SUCCESS {
+		return "", toError(err)
+	}
+	if size <= 1 {
+		return "", nil
+	}
+	buffer := make([]byte, int(size))
+	if err := C.clGetProgramBuildInfo(p.clProgram, device.nullableId(), C.CL_PROGRAM_BUILD_LOG, size, unsafe.Pointer(&buffer[0]), nil); err != C.CL_SUCCESS {
+		return "", toError(err)
+	}
 
-        // OpenCL strings are NUL-terminated, and the terminator is included in strN
-        // Go strings aren't NUL-terminated, so subtract 1 from the length
-        return C.GoStringN((*C.char)(unsafe.Pointer(&strC)), C.int(strN-1)), nil
+	// OpenCL strings are NUL-terminated, and the terminator is included in size
+	return strings.TrimRight(string(buffer[:size-1]), "\x00"), nil
+}
+
+// Collects the build status and log of each of devices, or of every device
+// of the program if devices is nil, and parses the compiler diagnostics.
+// Use it after a successful build to see the warnings.
+func (p *Program) BuildLogs(devices []*Device) (*BuildResult, error) {
+	if len(devices) == 0 {
+		devices = p.devices
+	}
+	if len(devices) == 0 {
+		var err error
+		if devices, err = p.GetDevices(); err != nil {
+			return nil, err
+		}
+	}
+	result := &BuildResult{Devices: make([]DeviceBuildLog, 0, len(devices))}
+	for _, dev := range devices {
+		status, err := p.GetBuildStatus(dev)
+		if err != nil {
+			return nil, err
+		}
+		log, err := p.GetBuildLog(dev)
+		if err != nil {
+			return nil, err
+		}
+		diags := ParseBuildLog(log)
+		for i := range diags {
+			diags[i].Device = dev
+		}
+		result.Devices = append(result.Devices, DeviceBuildLog{Device: dev, Status: status, Log: log, Diagnostics: diags})
+	}
+	return result, nil
+}
+
+// Builds the error returned after a failed build, compile or link.
+func (p *Program) buildError(devices []*Device) error {
+	result, err := p.BuildLogs(devices)
+	if err != nil {
+		return err
+	}
+	return result.buildError()
+}
+
+// The error of a failed build with these logs.
+func (r *BuildResult) buildError() error {
+	for _, dev := range r.Devices {
+		if dev.Log != "" {
+			return BuildError{Device: dev.Device, Message: dev.Log, Result: r}
+		}
+	}
+	return BuildError{Message: "build failed and produced no log entries", Result: r}
 }
 
 func (p *Program) GetProgramBinaryType(device *Device) (ProgramBinaryTypes, error) {
@@ -571,17 +660,20 @@ func (p *Program) GetDeviceCount() (int, error) {
 }
 
 func (p *Program) GetDevices() ([]*Device, error) {
-	var val C.cl_device_id
-	var arr []C.cl_device_id
-	var cnts C.size_t
-	if err := C.clGetProgramInfo(p.clProgram, C.CL_PROGRAM_DEVICES, C.size_t(unsafe.Sizeof(val)), (unsafe.Pointer)(&arr), &cnts); err != C.CL_SUCCESS {
-		panic("Should never fail")
+	var num C.cl_uint
+	if err := C.clGetProgramInfo(p.clProgram, C.CL_PROGRAM_NUM_DEVICES, C.size_t(unsafe.Sizeof(num)), unsafe.Pointer(&num), nil); err != C.CL_SUCCESS {
 		return nil, toError(err)
 	}
-
-	returnDevices := make([]*Device, int(cnts))
-	for i := 0; i < int(cnts); i++ {
-		returnDevices[i] = &Device{id: arr[i]}
+	if num == 0 {
+		return nil, nil
+	}
+	ids := make([]C.cl_device_id, int(num))
+	if err := C.clGetProgramInfo(p.clProgram, C.CL_PROGRAM_DEVICES, C.size_t(unsafe.Sizeof(ids[0]))*C.size_t(num), unsafe.Pointer(&ids[0]), nil); err != C.CL_SUCCESS {
+		return nil, toError(err)
+	}
+	returnDevices := make([]*Device, len(ids))
+	for i, id := range ids {
+		returnDevices[i] = &Device{id: id}
 	}
 	return returnDevices, nil
 }
@@ -600,34 +692,100 @@ func (p *Program) GetSource() (string, error) {
 }
 
 func (p *Program) GetBinarySizes() ([]int, error) {
-	var val C.size_t
-	var arr []C.size_t
-	if err := C.clGetProgramInfo(p.clProgram, C.CL_PROGRAM_BINARY_SIZES, C.size_t(unsafe.Sizeof(val)), (unsafe.Pointer)(&arr), &val); err != C.CL_SUCCESS {
-		panic("Should never fail")
+	var num C.cl_uint
+	if err := C.clGetProgramInfo(p.clProgram, C.CL_PROGRAM_NUM_DEVICES, C.size_t(unsafe.Sizeof(num)), unsafe.Pointer(&num), nil); err != C.CL_SUCCESS {
 		return nil, toError(err)
 	}
-
-	returnCount := make([]int, int(val))
-	for i := 0; i < int(val); i++ {
-		returnCount[i] = int(arr[i])
+	if num == 0 {
+		return nil, nil
+	}
+	sizes := make([]C.size_t, int(num))
+	if err := C.clGetProgramInfo(p.clProgram, C.CL_PROGRAM_BINARY_SIZES, C.size_t(unsafe.Sizeof(sizes[0]))*C.size_t(num), unsafe.Pointer(&sizes[0]), nil); err != C.CL_SUCCESS {
+		return nil, toError(err)
+	}
+	returnCount := make([]int, len(sizes))
+	for i, size := range sizes {
+		returnCount[i] = int(size)
 	}
 	return returnCount, nil
 }
 
-func (p *Program) GetBinaries() ([]*uint8, error) {
-	var item *uint8
-	var val C.size_t
-	var arr []*uint8
-	if err := C.clGetProgramInfo(p.clProgram, C.CL_PROGRAM_BINARIES, C.size_t(unsafe.Sizeof(item)), (unsafe.Pointer)(&arr), &val); err != C.CL_SUCCESS {
-		panic("Should never fail")
+// Copies out the binary of the program for each device, in the order
+// returned by GetDevices. Devices without a binary get a nil entry.
+func (p *Program) GetBinaries() ([][]byte, error) {
+	var num C.cl_uint
+	if err := C.clGetProgramInfo(p.clProgram, C.CL_PROGRAM_NUM_DEVICES, C.size_t(unsafe.Sizeof(num)), unsafe.Pointer(&num), nil); err != C.CL_SUCCESS {
 		return nil, toError(err)
 	}
-
-	returnBinaries := make([]*uint8, int(val))
-	for i := 0; i < int(val); i++ {
-		returnBinaries[i] = arr[i]
+	if num == 0 {
+		return nil, nil
+	}
+	sizes := make([]C.size_t, int(num))
+	if err := C.clGetProgramInfo(p.clProgram, C.CL_PROGRAM_BINARY_SIZES, C.size_t(unsafe.Sizeof(sizes[0]))*C.size_t(num), unsafe.Pointer(&sizes[0]), nil); err != C.CL_SUCCESS {
+		return nil, toError(err)
+	}
+	// The driver writes through the pointers in this table, so both the
+	// table and the buffers it points to have to live in C memory.
+	ptrSize := C.size_t(unsafe.Sizeof(uintptr(0)))
+	table := C.calloc(C.size_t(num), ptrSize)
+	defer C.free(table)
+	ptrs := unsafe.Slice((**C.uchar)(table), int(num))
+	for i, size := range sizes {
+		if size > 0 {
+			ptrs[i] = (*C.uchar)(C.malloc(size))
+			defer C.free(unsafe.Pointer(ptrs[i]))
+		}
+	}
+	if err := C.clGetProgramInfo(p.clProgram, C.CL_PROGRAM_BINARIES, ptrSize*C.size_t(num), table, nil); err != C.CL_SUCCESS {
+		return nil, toError(err)
+	}
+	bins := make([][]byte, int(num))
+	for i, size := range sizes {
+		if size > 0 {
+			bins[i] = C.GoBytes(unsafe.Pointer(ptrs[i]), C.int(size))
+		}
+	}
+	return bins, nil
+}
+
+// Creates a program from one binary per device, as returned by GetBinaries.
+// Besides the overall error it returns the load status of each binary as
+// reported by the driver (nil for binaries that loaded), so that a caller
+// can tell which device rejected its binary. The program still has to be
+// built with BuildProgram before kernels can be created.
+func (ctx *Context) CreateProgramWithBinary(devices []*Device, bins [][]byte) (*Program, []error, error) {
+	if len(devices) == 0 || len(devices) != len(bins) {
+		return nil, nil, ErrInvalidValue
+	}
+	deviceList := buildDeviceIdList(devices)
+	lengths := make([]C.size_t, len(bins))
+	table := C.calloc(C.size_t(len(bins)), C.size_t(unsafe.Sizeof(uintptr(0))))
+	defer C.free(table)
+	ptrs := unsafe.Slice((**C.uchar)(table), len(bins))
+	for i, bin := range bins {
+		if len(bin) == 0 {
+			return nil, nil, ErrInvalidValue
+		}
+		lengths[i] = C.size_t(len(bin))
+		ptrs[i] = (*C.uchar)(C.CBytes(bin))
+		defer C.free(unsafe.Pointer(ptrs[i]))
+	}
+	binStatus := make([]C.cl_int, len(bins))
+	var err C.cl_int
+	clProgram := C.clCreateProgramWithBinary(ctx.clContext, C.cl_uint(len(devices)), &deviceList[0], &lengths[0], (**C.uchar)(table), &binStatus[0], &err)
+	statuses := make([]error, len(binStatus))
+	for i, status := range binStatus {
+		statuses[i] = toError(status)
+	}
+	if err != C.CL_SUCCESS {
+		return nil, statuses, toError(err)
+	}
+	if clProgram == nil {
+		return nil, statuses, ErrUnknown
 	}
-	return returnBinaries, nil
+	program := &Program{clProgram: clProgram, devices: devices}
+	runtime.SetFinalizer(program, releaseProgram)
+	return program, statuses, nil
 }
 
 func (p *Program) GetKernelCounts() (int, error) {
@@ -639,58 +797,141 @@ func (p *Program) GetKernelCounts() (int, error) {
 	return int(val), nil
 }
 
-func (p *Program) GetKernelNames() (string, error) {
-        var strC [1024]C.char
-        var strN C.size_t
-        if err := C.clGetProgramInfo(p.clProgram, C.CL_PROGRAM_KERNEL_NAMES, 1024, unsafe.Pointer(&strC), &strN); err != C.CL_SUCCESS {
-                panic("Should never fail")
-                return "", toError(err)
-        }
+// Names of the kernel functions of the built program.
+func (p *Program) GetKernelNames() ([]string, error) {
+	var size C.size_t
+	if err := C.clGetProgramInfo(p.clProgram, C.CL_PROGRAM_KERNEL_NAMES, 0, nil, &size); err != C.CL_SUCCESS {
+		return nil, toError(err)
+	}
+	if size <= 1 {
+		return nil, nil
+	}
+	buf := make([]byte, size)
+	if err := C.clGetProgramInfo(p.clProgram, C.CL_PROGRAM_KERNEL_NAMES, size, unsafe.Pointer(&buf[0]), nil); err != C.CL_SUCCESS {
+		return nil, toError(err)
+	}
+	// The names are separated by semicolons and NUL-terminated
+	return strings.Split(string(buf[:size-1]), ";"), nil
+}
 
-        // OpenCL strings are NUL-terminated, and the terminator is included in strN
-        // Go strings aren't NUL-terminated, so subtract 1 from the length
-        return C.GoStringN((*C.char)(unsafe.Pointer(&strC)), C.int(strN-1)), nil
+// Checks that every device of the program supports OpenCL major.minor.
+func (p *Program) requireVersion(feature string, major, minor int) error {
+	devices := p.devices
+	if len(devices) == 0 {
+		var err error
+		if devices, err = p.GetDevices(); err != nil {
+			return err
+		}
+	}
+	for _, d := range devices {
+		if !d.HasVersion(major, minor) {
+			return ErrVersionRequired{Feature: feature, Major: major, Minor: minor, Device: d}
+		}
+	}
+	return nil
 }
 
-func (ctx *Context) CreateProgramWithBinary(deviceList []*Device, program_lengths []int, program_binaries []*uint8) (*Program, error) {
-	var binary_in []*C.uchar
-	device_list_in := make([]C.cl_device_id, len(deviceList))
-	binary_lengths := make([]C.size_t, len(program_lengths))
-	defer C.free(unsafe.Pointer(&binary_in))
-	defer C.free(unsafe.Pointer(&binary_lengths))
-	defer C.free(unsafe.Pointer(&device_list_in))
-	var binErr []C.cl_int
-	var err C.cl_int
-        for i, bin_val := range program_binaries {
-		binary_lengths[i] = C.size_t(program_lengths[i])
-		binary_in[i] = (*C.uchar)(bin_val)
-        }
-	for i, devItem := range deviceList {
-		device_list_in[i] = devItem.id
-	}
-        clProgram := C.clCreateProgramWithBinary(ctx.clContext, C.cl_uint(len(deviceList)), &device_list_in[0], &binary_lengths[0], &binary_in[0], &binErr[0], &err)
-	for i := range binary_lengths {
-		if binErr[i] != C.CL_SUCCESS {
-			errMsg := int(binErr[i])
-			switch errMsg {
-			default:
-				fmt.Printf("Unknown error when loading binary %d \n", i)
-			case C.CL_INVALID_VALUE:
-				fmt.Printf("Loading empty binary %d \n", i)
-			case C.CL_INVALID_BINARY:
-				fmt.Printf("Loading an invalid binary %d \n", i)
-			}
+// Checks that the platform of the program supports OpenCL major.minor and
+// that the loaded OpenCL library exports the entry point fn.
+func (p *Program) requireFunction(fn string, major, minor int) error {
+	devices := p.devices
+	if len(devices) == 0 {
+		var err error
+		if devices, err = p.GetDevices(); err != nil {
+			return err
 		}
 	}
-        if err != C.CL_SUCCESS {
-                return nil, toError(err)
-        }
-        if clProgram == nil {
-                return nil, ErrUnknown
-        }
-        program := &Program{clProgram: clProgram, devices: ctx.devices}
-        runtime.SetFinalizer(program, releaseProgram)
-        return program, nil
+	if len(devices) == 0 {
+		return ErrInvalidProgram
+	}
+	platform := devices[0].Platform()
+	if !platform.HasVersion(major, minor) {
+		return ErrVersionRequired{Feature: fn, Major: major, Minor: minor, Platform: platform}
+	}
+	if !hasCoreFunction(fn) {
+		return ErrVersionRequired{Feature: fn, Major: major, Minor: minor}
+	}
+	return nil
+}
+
+// Sets the value of the SPIR-V specialization constant id of a program
+// created with CreateProgramWithIL, for use by the next BuildProgram or
+// CompileProgram. value is a Go scalar of the size of the constant, e.g.
+// int32 for an OpenCL int; bools are passed as a single byte. Requires
+// OpenCL 2.2.
+func (p *Program) SetSpecializationConstant(id uint32, value interface{}) error {
+	if err := p.requireFunction("clSetProgramSpecializationConstant", 2, 2); err != nil {
+		return err
+	}
+	var data []byte
+	if b, ok := value.(bool); ok {
+		data = []byte{0}
+		if b {
+			data[0] = 1
+		}
+	} else {
+		var err error
+		if data, err = marshalArg(value); err != nil {
+			return err
+		}
+	}
+	return toError(C.CLSetProgramSpecializationConstant(p.clProgram, C.cl_uint(id), C.size_t(len(data)), unsafe.Pointer(&data[0])))
+}
+
+// The intermediate language the program was created from with
+// CreateProgramWithIL, or nil for other programs. Requires OpenCL 2.1.
+func (p *Program) GetIL() ([]byte, error) {
+	if err := p.requireVersion("CL_PROGRAM_IL", 2, 1); err != nil {
+		return nil, err
+	}
+	var size C.size_t
+	if err := C.clGetProgramInfo(p.clProgram, C.CL_PROGRAM_IL, 0, nil, &size); err != C.CL_SUCCESS {
+		return nil, toError(err)
+	}
+	if size == 0 {
+		return nil, nil
+	}
+	il := make([]byte, size)
+	if err := C.clGetProgramInfo(p.clProgram, C.CL_PROGRAM_IL, size, unsafe.Pointer(&il[0]), nil); err != C.CL_SUCCESS {
+		return nil, toError(err)
+	}
+	return il, nil
+}
+
+func (p *Program) getInfoBool(param C.cl_program_info, feature string) (bool, error) {
+	if err := p.requireVersion(feature, 2, 2); err != nil {
+		return false, err
+	}
+	var val C.cl_bool
+	if err := C.clGetProgramInfo(p.clProgram, param, C.size_t(unsafe.Sizeof(val)), unsafe.Pointer(&val), nil); err != C.CL_SUCCESS {
+		return false, toError(err)
+	}
+	return val == C.CL_TRUE, nil
+}
+
+// Whether the program has program scope global constructors. Requires
+// OpenCL 2.2.
+func (p *Program) ScopeGlobalCtorsPresent() (bool, error) {
+	return p.getInfoBool(C.CL_PROGRAM_SCOPE_GLOBAL_CTORS_PRESENT, "CL_PROGRAM_SCOPE_GLOBAL_CTORS_PRESENT")
+}
+
+// Whether the program has program scope global destructors. Requires
+// OpenCL 2.2.
+func (p *Program) ScopeGlobalDtorsPresent() (bool, error) {
+	return p.getInfoBool(C.CL_PROGRAM_SCOPE_GLOBAL_DTORS_PRESENT, "CL_PROGRAM_SCOPE_GLOBAL_DTORS_PRESENT")
+}
+
+// Total size in bytes of the program scope global variables of the program
+// built for device. Requires OpenCL 2.0.
+func (p *Program) GetBuildGlobalVariableTotalSize(device *Device) (int, error) {
+	if !device.HasVersion(2, 0) {
+		return 0, ErrVersionRequired{Feature: "CL_PROGRAM_BUILD_GLOBAL_VARIABLE_TOTAL_SIZE", Major: 2, Minor: 0, Device: device}
+	}
+	var val C.size_t
+	if err := C.clGetProgramBuildInfo(p.clProgram, device.id, C.CL_PROGRAM_BUILD_GLOBAL_VARIABLE_TOTAL_SIZE, C.size_t(unsafe.Sizeof(val)), unsafe.Pointer(&val), nil); err != C.CL_SUCCESS {
+		return 0, toError(err)
+	}
+	return int(val), nil
 }
 
 func (pf *Platform) UnloadCompiler() error {
@@ -0,0 +1,184 @@
+package cl
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+)
+
+//////////////// Abstract Types ////////////////
+
+// A concurrency-safe set of programs built on one context, keyed by their
+// sources, options and devices, so that independent components asking for
+// the same program share a single build. Obtain it with Context.Programs.
+type ProgramRegistry struct {
+	// A copy of the context without its finalizer, so that the registry
+	// does not keep the Context holding it reachable. Nil once the context
+	// is released.
+	ctx     *Context
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*registryEntry
+}
+
+type registryEntry struct {
+	done    chan struct{}
+	program *Program // nil once released by Clear, guarded by the registry
+	err     error
+}
+
+//////////////// Supporting Types ////////////////
+
+// Guards the lazy creation of Context.registry.
+var registryMu sync.Mutex
+
+//////////////// Basic Functions ////////////////
+
+func registryKey(sources []string, devices []*Device, options string) [sha256.Size]byte {
+	h := sha256.New()
+	for _, src := range sources {
+		hashString(h, src)
+	}
+	hashString(h, options)
+	for _, dev := range devices {
+		hashString(h, fmt.Sprintf("%p", dev.id))
+	}
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+	return key
+}
+
+//////////////// Abstract Functions ////////////////
+
+func (ctx *Context) loadRegistry() *ProgramRegistry {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return ctx.registry
+}
+
+// The program registry of the context, created on first use. Releasing the
+// context releases the references held by the registry.
+func (ctx *Context) Programs() *ProgramRegistry {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if ctx.registry == nil {
+		view := &Context{clContext: ctx.clContext, devices: ctx.devices}
+		ctx.registry = &ProgramRegistry{ctx: view, entries: map[[sha256.Size]byte]*registryEntry{}}
+	}
+	return ctx.registry
+}
+
+// Returns the program built from sources with options for devices (all
+// devices of the context if nil), building it if no component has asked for
+// it before. Callers asking while the build is running wait for it instead of
+// starting another. The returned program is a retained reference that the
+// caller owns and should Release; failed builds are not remembered.
+func (r *ProgramRegistry) Program(sources []string, devices []*Device, options string) (*Program, error) {
+	for {
+		program, cleared, err := r.program(sources, devices, options)
+		if !cleared {
+			return program, err
+		}
+	}
+}
+
+// Returns the program as Program does, or true if Clear released it before
+// a reference could be taken, in which case it has to be asked for again.
+func (r *ProgramRegistry) program(sources []string, devices []*Device, options string) (*Program, bool, error) {
+	r.mu.Lock()
+	ctx := r.ctx
+	if ctx == nil {
+		r.mu.Unlock()
+		return nil, false, ErrInvalidContext
+	}
+	if len(devices) == 0 {
+		devices = ctx.devices
+	}
+	key := registryKey(sources, devices, options)
+	e, ok := r.entries[key]
+	if !ok {
+		e = &registryEntry{done: make(chan struct{})}
+		r.entries[key] = e
+	}
+	r.mu.Unlock()
+
+	if !ok {
+		e.program, e.err = buildRegistryProgram(ctx, sources, devices, options)
+		if e.err != nil {
+			r.mu.Lock()
+			if r.entries[key] == e {
+				delete(r.entries, key)
+			}
+			r.mu.Unlock()
+		}
+		close(e.done)
+	}
+	<-e.done
+	if e.err != nil {
+		return nil, false, e.err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e.program == nil {
+		return nil, true, nil
+	}
+	return e.program.retained(), false, nil
+}
+
+func buildRegistryProgram(ctx *Context, sources []string, devices []*Device, options string) (*Program, error) {
+	program, err := ctx.CreateProgramWithSource(sources)
+	if err != nil {
+		return nil, err
+	}
+	if err := program.BuildProgram(devices, options); err != nil {
+		program.Release()
+		return nil, err
+	}
+	return program, nil
+}
+
+// Returns a new kernel called name from the shared program built as by
+// Program. Every call creates its own kernel object, since kernel arguments
+// are part of the kernel object and cannot be shared between callers.
+func (r *ProgramRegistry) Kernel(sources []string, devices []*Device, options, name string) (*Kernel, error) {
+	program, err := r.Program(sources, devices, options)
+	if err != nil {
+		return nil, err
+	}
+	defer program.Release()
+	return program.CreateKernel(name)
+}
+
+// Number of programs built or being built.
+func (r *ProgramRegistry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+// Drops every program from the registry and releases the references it
+// holds. References handed out earlier stay valid until released.
+func (r *ProgramRegistry) Clear() {
+	r.mu.Lock()
+	entries := r.entries
+	r.entries = map[[sha256.Size]byte]*registryEntry{}
+	r.mu.Unlock()
+	for _, e := range entries {
+		<-e.done
+		// Callers take their reference under the lock, so none can be
+		// taken from a released program
+		r.mu.Lock()
+		if e.program != nil {
+			e.program.Release()
+			e.program = nil
+		}
+		r.mu.Unlock()
+	}
+}
+
+// Clears the registry for good when its context is released.
+func (r *ProgramRegistry) close() {
+	r.mu.Lock()
+	r.ctx = nil
+	r.mu.Unlock()
+	r.Clear()
+}
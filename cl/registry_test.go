@@ -0,0 +1,65 @@
+package cl
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProgramRegistryDoesNotKeepContext(t *testing.T) {
+	finalized := make(chan struct{})
+	func() {
+		ctx := &Context{}
+		ctx.Programs()
+		runtime.SetFinalizer(ctx, func(*Context) { close(finalized) })
+	}()
+	deadline := time.After(5 * time.Second)
+	for {
+		runtime.GC()
+		select {
+		case <-finalized:
+			return
+		case <-deadline:
+			t.Fatal("context holding a registry was never finalized")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestProgramRegistryAfterRelease(t *testing.T) {
+	ctx := &Context{}
+	r := ctx.Programs()
+	ctx.Release()
+	if _, err := r.Program([]string{"__kernel void k() {}"}, nil, ""); err != ErrInvalidContext {
+		t.Errorf("Program after Release: got %v, expected ErrInvalidContext", err)
+	}
+}
+
+func TestProgramRegistryConcurrentClear(t *testing.T) {
+	sources := []string{"__kernel void k() {}"}
+	for round := 0; round < 50; round++ {
+		ctx := &Context{}
+		r := ctx.Programs()
+		done := make(chan struct{})
+		close(done)
+		r.entries[registryKey(sources, nil, "")] = &registryEntry{done: done, program: &Program{}}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				program, err := r.Program(sources, nil, "")
+				if (program == nil) == (err == nil) || (err != nil && err != ErrInvalidContext) {
+					t.Errorf("Program during Release: got %v, %v", program, err)
+				}
+			}()
+		}
+		ctx.Release()
+		wg.Wait()
+		if r.Len() != 0 {
+			t.Fatalf("registry not cleared")
+		}
+	}
+}
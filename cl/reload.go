@@ -0,0 +1,243 @@
+package cl
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+//////////////// Abstract Types ////////////////
+
+// A program rebuilt from its source files whenever they change, for use
+// while developing kernels. The files are polled; after a successful
+// rebuild the new program and its kernels replace the old ones for every
+// later call to Program or Kernel. When a rebuild fails the previous build
+// stays in use and the error is reported through Err and Reloads.
+type ReloadingProgram struct {
+	ctx     *Context
+	devices []*Device
+	files   []string
+	options string
+
+	buildMu    sync.Mutex // serialises reloads, so an older build never replaces a newer one
+	mu         sync.RWMutex
+	program    *Program
+	kernels    map[string]*Kernel
+	generation int
+	err        error
+	stamps     []fileStamp
+
+	reloads   chan error
+	stop      chan struct{}
+	closeOnce sync.Once
+	stopped   sync.WaitGroup
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+//////////////// Basic Functions ////////////////
+
+// Reads files along with their stamps. Each file is stamped before it is
+// read, so an edit landing in between leaves a stale stamp and is rebuilt
+// again rather than never.
+func stampFiles(files []string) ([]fileStamp, []string, error) {
+	stamps := make([]fileStamp, len(files))
+	sources := make([]string, len(files))
+	for i, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		stamps[i] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		sources[i] = string(data)
+	}
+	return stamps, sources, nil
+}
+
+// Sends err on ch, dropping an unread earlier value so that a slow reader
+// only sees the latest one.
+func sendLatest(ch chan error, err error) {
+	for {
+		select {
+		case ch <- err:
+			return
+		default:
+		}
+		select {
+		case <-ch:
+		default:
+		}
+	}
+}
+
+//////////////// Abstract Functions ////////////////
+
+// Builds the program from files and rebuilds it whenever one of them
+// changes, checking every interval (a second if zero). The first build must
+// succeed. Call Close to stop watching.
+func (ctx *Context) WatchProgram(files []string, devices []*Device, options string, interval time.Duration) (*ReloadingProgram, error) {
+	if len(devices) == 0 {
+		devices = ctx.devices
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+	rp := &ReloadingProgram{
+		ctx:     ctx,
+		devices: devices,
+		files:   files,
+		options: options,
+		reloads: make(chan error, 1),
+		stop:    make(chan struct{}),
+	}
+	stamps, sources, err := stampFiles(files)
+	if err != nil {
+		return nil, err
+	}
+	program, err := rp.build(sources)
+	if err != nil {
+		return nil, err
+	}
+	rp.program, rp.kernels, rp.stamps, rp.generation = program, map[string]*Kernel{}, stamps, 1
+
+	rp.stopped.Add(1)
+	go rp.watch(interval)
+	return rp, nil
+}
+
+func (rp *ReloadingProgram) build(sources []string) (*Program, error) {
+	program, err := rp.ctx.CreateProgramWithSource(sources)
+	if err != nil {
+		return nil, err
+	}
+	if err := program.BuildProgram(rp.devices, rp.options); err != nil {
+		program.Release()
+		return nil, err
+	}
+	return program, nil
+}
+
+func (rp *ReloadingProgram) watch(interval time.Duration) {
+	defer rp.stopped.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rp.stop:
+			return
+		case <-ticker.C:
+			if rp.changed() {
+				rp.Reload()
+			}
+		}
+	}
+}
+
+// Whether any file differs from the last attempted build. Files that cannot
+// be read count as unchanged, as editors often replace files by renaming.
+func (rp *ReloadingProgram) changed() bool {
+	rp.mu.RLock()
+	defer rp.mu.RUnlock()
+	for i, f := range rp.files {
+		info, err := os.Stat(f)
+		if err != nil {
+			continue
+		}
+		if !info.ModTime().Equal(rp.stamps[i].modTime) || info.Size() != rp.stamps[i].size {
+			return true
+		}
+	}
+	return false
+}
+
+// Rebuilds the program now. On success the new program replaces the current
+// one; on failure the current one is kept. The outcome is also sent on
+// Reloads. Concurrent reloads run one after the other.
+func (rp *ReloadingProgram) Reload() error {
+	rp.buildMu.Lock()
+	defer rp.buildMu.Unlock()
+	stamps, sources, err := stampFiles(rp.files)
+	if err == nil {
+		var program *Program
+		if program, err = rp.build(sources); err == nil {
+			rp.mu.Lock()
+			rp.program, rp.kernels = program, map[string]*Kernel{}
+			rp.generation++
+			rp.mu.Unlock()
+		}
+	}
+	rp.mu.Lock()
+	if stamps != nil {
+		rp.stamps = stamps
+	}
+	rp.err = err
+	rp.mu.Unlock()
+	sendLatest(rp.reloads, err)
+	return err
+}
+
+// The current program. Programs replaced by a reload are released by their
+// finalizer once no longer referenced.
+func (rp *ReloadingProgram) Program() *Program {
+	rp.mu.RLock()
+	defer rp.mu.RUnlock()
+	return rp.program
+}
+
+// The kernel called name of the current program, created on first use after
+// each reload. Kernels are shared by all callers of the same generation, so
+// concurrent launches must synchronise their SetArg calls.
+func (rp *ReloadingProgram) Kernel(name string) (*Kernel, error) {
+	rp.mu.RLock()
+	k, ok := rp.kernels[name]
+	rp.mu.RUnlock()
+	if ok {
+		return k, nil
+	}
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if k, ok := rp.kernels[name]; ok {
+		return k, nil
+	}
+	k, err := rp.program.CreateKernel(name)
+	if err != nil {
+		return nil, err
+	}
+	rp.kernels[name] = k
+	return k, nil
+}
+
+// Number of successful builds, starting at 1 for the initial one.
+func (rp *ReloadingProgram) Generation() int {
+	rp.mu.RLock()
+	defer rp.mu.RUnlock()
+	return rp.generation
+}
+
+// The error of the last rebuild, usually a BuildError, or nil if it
+// succeeded.
+func (rp *ReloadingProgram) Err() error {
+	rp.mu.RLock()
+	defer rp.mu.RUnlock()
+	return rp.err
+}
+
+// Receives the outcome of each rebuild: nil after a successful one, the
+// error otherwise. Only the latest outcome is kept if it is not read.
+func (rp *ReloadingProgram) Reloads() <-chan error {
+	return rp.reloads
+}
+
+// Stops watching the files. The current program stays usable.
+func (rp *ReloadingProgram) Close() {
+	rp.closeOnce.Do(func() { close(rp.stop) })
+	rp.stopped.Wait()
+}
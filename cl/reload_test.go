@@ -0,0 +1,75 @@
+package cl
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStampFiles(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.cl"), filepath.Join(dir, "b.cl")
+	if err := os.WriteFile(a, []byte("__kernel void a() {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("__kernel void b() {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stamps, sources, err := stampFiles([]string{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) != 2 || sources[1] != "__kernel void b() {}" || stamps[0].size != 20 {
+		t.Fatalf("unexpected stamps %v and sources %q", stamps, sources)
+	}
+	if _, _, err := stampFiles([]string{a, filepath.Join(dir, "missing.cl")}); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing file: got %v, expected ErrNotExist", err)
+	}
+
+	rp := &ReloadingProgram{files: []string{a, b}, stamps: stamps}
+	if rp.changed() {
+		t.Errorf("changed reported without an edit")
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(b, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if !rp.changed() {
+		t.Errorf("new modification time not noticed")
+	}
+	if rp.stamps, _, err = stampFiles(rp.files); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(a, []byte("__kernel void a(int n) {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(a, rp.stamps[0].modTime, rp.stamps[0].modTime); err != nil {
+		t.Fatal(err)
+	}
+	if !rp.changed() {
+		t.Errorf("new size not noticed")
+	}
+	os.Remove(a)
+	rp.stamps, _, _ = stampFiles([]string{b, b})
+	if rp.changed() {
+		t.Errorf("a missing file counted as changed")
+	}
+}
+
+func TestSendLatest(t *testing.T) {
+	ch := make(chan error, 1)
+	first, second := errors.New("first"), errors.New("second")
+	sendLatest(ch, first)
+	sendLatest(ch, second)
+	sendLatest(ch, nil)
+	if err := <-ch; err != nil {
+		t.Errorf("got %v, expected the latest outcome", err)
+	}
+	select {
+	case err := <-ch:
+		t.Errorf("unexpected outcome %v", err)
+	default:
+	}
+}
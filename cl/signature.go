@@ -0,0 +1,352 @@
+package cl
+
+/*
+#include "./opencl.h"
+
+#ifndef CL_KERNEL_ARG_TYPE_PIPE
+#define CL_KERNEL_ARG_TYPE_PIPE (1 << 3)
+#endif
+*/
+import "C"
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+//////////////// Basic Types ////////////////
+
+// Address space of a kernel argument.
+type ArgAddress int
+
+const (
+	ArgAddressGlobal   ArgAddress = C.CL_KERNEL_ARG_ADDRESS_GLOBAL
+	ArgAddressLocal    ArgAddress = C.CL_KERNEL_ARG_ADDRESS_LOCAL
+	ArgAddressConstant ArgAddress = C.CL_KERNEL_ARG_ADDRESS_CONSTANT
+	ArgAddressPrivate  ArgAddress = C.CL_KERNEL_ARG_ADDRESS_PRIVATE
+)
+
+func (a ArgAddress) String() string {
+	switch a {
+	case ArgAddressGlobal:
+		return "Global"
+	case ArgAddressLocal:
+		return "Local"
+	case ArgAddressConstant:
+		return "Constant"
+	case ArgAddressPrivate:
+		return "Private"
+	}
+	return fmt.Sprintf("Unknown(%x)", int(a))
+}
+
+// Access qualifier of an image or pipe kernel argument; ArgAccessNone for
+// every other argument.
+type ArgAccess int
+
+const (
+	ArgAccessReadOnly  ArgAccess = C.CL_KERNEL_ARG_ACCESS_READ_ONLY
+	ArgAccessWriteOnly ArgAccess = C.CL_KERNEL_ARG_ACCESS_WRITE_ONLY
+	ArgAccessReadWrite ArgAccess = C.CL_KERNEL_ARG_ACCESS_READ_WRITE
+	ArgAccessNone      ArgAccess = C.CL_KERNEL_ARG_ACCESS_NONE
+)
+
+func (a ArgAccess) String() string {
+	switch a {
+	case ArgAccessReadOnly:
+		return "ReadOnly"
+	case ArgAccessWriteOnly:
+		return "WriteOnly"
+	case ArgAccessReadWrite:
+		return "ReadWrite"
+	case ArgAccessNone:
+		return "None"
+	}
+	return fmt.Sprintf("Unknown(%x)", int(a))
+}
+
+// Type qualifiers of a kernel argument, a combination of the flags below.
+type ArgTypeQualifier int
+
+const (
+	ArgTypeNone     ArgTypeQualifier = C.CL_KERNEL_ARG_TYPE_NONE
+	ArgTypeConst    ArgTypeQualifier = C.CL_KERNEL_ARG_TYPE_CONST
+	ArgTypeRestrict ArgTypeQualifier = C.CL_KERNEL_ARG_TYPE_RESTRICT
+	ArgTypeVolatile ArgTypeQualifier = C.CL_KERNEL_ARG_TYPE_VOLATILE
+	ArgTypePipe     ArgTypeQualifier = C.CL_KERNEL_ARG_TYPE_PIPE // OpenCL 2.0
+)
+
+func (q ArgTypeQualifier) Const() bool {
+	return q&ArgTypeConst != 0
+}
+
+func (q ArgTypeQualifier) Restrict() bool {
+	return q&ArgTypeRestrict != 0
+}
+
+func (q ArgTypeQualifier) Volatile() bool {
+	return q&ArgTypeVolatile != 0
+}
+
+func (q ArgTypeQualifier) Pipe() bool {
+	return q&ArgTypePipe != 0
+}
+
+func (q ArgTypeQualifier) String() string {
+	var parts []string
+	if q.Const() {
+		parts = append(parts, "Const")
+	}
+	if q.Restrict() {
+		parts = append(parts, "Restrict")
+	}
+	if q.Volatile() {
+		parts = append(parts, "Volatile")
+	}
+	if q.Pipe() {
+		parts = append(parts, "Pipe")
+	}
+	if parts == nil {
+		return "None"
+	}
+	return strings.Join(parts, "|")
+}
+
+// Kind of value a kernel argument takes.
+type ArgKind int
+
+const (
+	ArgKindScalar  ArgKind = iota // a built-in scalar such as float
+	ArgKindVector                 // a built-in vector such as float4
+	ArgKindPointer                // a pointer to global, constant or local memory
+	ArgKindImage                  // an image type such as image2d_t
+	ArgKindSampler                // sampler_t
+	ArgKindPipe                   // a pipe
+	ArgKindOther                  // a struct, union or other type declared by the program
+)
+
+func (k ArgKind) String() string {
+	switch k {
+	case ArgKindScalar:
+		return "Scalar"
+	case ArgKindVector:
+		return "Vector"
+	case ArgKindPointer:
+		return "Pointer"
+	case ArgKindImage:
+		return "Image"
+	case ArgKindSampler:
+		return "Sampler"
+	case ArgKindPipe:
+		return "Pipe"
+	case ArgKindOther:
+		return "Other"
+	}
+	return fmt.Sprintf("Unknown(%d)", int(k))
+}
+
+// Returned by SetArgByName when the kernel has no argument of that name.
+type ErrUnknownArgument struct {
+	Kernel string
+	Name   string
+}
+
+func (e ErrUnknownArgument) Error() string {
+	return fmt.Sprintf("cl: kernel %s has no argument named %q", e.Kernel, e.Name)
+}
+
+//////////////// Abstract Types ////////////////
+
+// Description of one kernel argument. TypeName is the type as reported by
+// the driver, e.g. "float*"; BaseType is the type without the pointer and
+// vector width, e.g. "float" for both "float*" and "float4". Width is the
+// number of vector components, 1 for scalars and pointers to scalars.
+type KernelArg struct {
+	Index      int
+	Name       string
+	TypeName   string
+	BaseType   string
+	Kind       ArgKind
+	Width      int
+	Address    ArgAddress
+	Access     ArgAccess
+	Qualifiers ArgTypeQualifier
+}
+
+// Description of a kernel and its arguments.
+type KernelSignature struct {
+	Name string
+	Args []KernelArg
+}
+
+//////////////// Basic Functions ////////////////
+
+// Splits a type name reported by the driver into its kind, base type and
+// vector width.
+func parseArgType(typeName string, qualifiers ArgTypeQualifier) (ArgKind, string, int) {
+	base := strings.TrimSuffix(typeName, "*")
+	kind := ArgKindOther
+	switch {
+	case qualifiers.Pipe():
+		kind = ArgKindPipe
+	case base != typeName:
+		kind = ArgKindPointer
+	case strings.HasPrefix(typeName, "image"):
+		return ArgKindImage, typeName, 1
+	case typeName == "sampler_t":
+		return ArgKindSampler, typeName, 1
+	}
+	width := 1
+	if isBuiltinTypeName(base) {
+		scalar := strings.TrimRight(base, "0123456789")
+		if scalar != base {
+			width, _ = strconv.Atoi(base[len(scalar):])
+			base = scalar
+			if kind == ArgKindOther {
+				kind = ArgKindVector
+			}
+		} else if kind == ArgKindOther {
+			kind = ArgKindScalar
+		}
+	}
+	return kind, base, width
+}
+
+// The argument as it would be declared in OpenCL C, e.g.
+// "__global const float* restrict x".
+func (a KernelArg) String() string {
+	var parts []string
+	switch a.Address {
+	case ArgAddressGlobal:
+		parts = append(parts, "__global")
+	case ArgAddressConstant:
+		parts = append(parts, "__constant")
+	case ArgAddressLocal:
+		parts = append(parts, "__local")
+	}
+	switch a.Access {
+	case ArgAccessReadOnly:
+		parts = append(parts, "__read_only")
+	case ArgAccessWriteOnly:
+		parts = append(parts, "__write_only")
+	case ArgAccessReadWrite:
+		parts = append(parts, "__read_write")
+	}
+	if a.Qualifiers.Const() {
+		parts = append(parts, "const")
+	}
+	if a.Qualifiers.Volatile() {
+		parts = append(parts, "volatile")
+	}
+	if a.Qualifiers.Pipe() {
+		parts = append(parts, "pipe")
+	}
+	parts = append(parts, a.TypeName)
+	if a.Qualifiers.Restrict() {
+		parts = append(parts, "restrict")
+	}
+	parts = append(parts, a.Name)
+	return strings.Join(parts, " ")
+}
+
+// The argument called name, if any.
+func (s *KernelSignature) Arg(name string) (KernelArg, bool) {
+	for _, a := range s.Args {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return KernelArg{}, false
+}
+
+func (s *KernelSignature) String() string {
+	args := make([]string, len(s.Args))
+	for i, a := range s.Args {
+		args[i] = a.String()
+	}
+	return fmt.Sprintf("__kernel void %s(%s)", s.Name, strings.Join(args, ", "))
+}
+
+//////////////// Abstract Functions ////////////////
+
+func (k *Kernel) ArgAddress(index int) (ArgAddress, error) {
+	var val C.cl_kernel_arg_address_qualifier
+	err := C.clGetKernelArgInfo(k.clKernel, C.cl_uint(index), C.CL_KERNEL_ARG_ADDRESS_QUALIFIER, C.size_t(unsafe.Sizeof(val)), unsafe.Pointer(&val), nil)
+	return ArgAddress(val), toError(err)
+}
+
+func (k *Kernel) ArgAccess(index int) (ArgAccess, error) {
+	var val C.cl_kernel_arg_access_qualifier
+	err := C.clGetKernelArgInfo(k.clKernel, C.cl_uint(index), C.CL_KERNEL_ARG_ACCESS_QUALIFIER, C.size_t(unsafe.Sizeof(val)), unsafe.Pointer(&val), nil)
+	return ArgAccess(val), toError(err)
+}
+
+func (k *Kernel) ArgTypeQualifier(index int) (ArgTypeQualifier, error) {
+	var val C.cl_kernel_arg_type_qualifier
+	err := C.clGetKernelArgInfo(k.clKernel, C.cl_uint(index), C.CL_KERNEL_ARG_TYPE_QUALIFIER, C.size_t(unsafe.Sizeof(val)), unsafe.Pointer(&val), nil)
+	return ArgTypeQualifier(val), toError(err)
+}
+
+// Describes the kernel and every argument. The program must have been built
+// with -cl-kernel-arg-info (see BuildOptions.KernelArgInfo), otherwise this
+// fails with ErrKernelArgInfoNotAvailable.
+func (k *Kernel) Signature() (*KernelSignature, error) {
+	name, err := k.FunctionName()
+	if err != nil {
+		return nil, err
+	}
+	num, err := k.NumArgs()
+	if err != nil {
+		return nil, err
+	}
+	sig := &KernelSignature{Name: name, Args: make([]KernelArg, num)}
+	for i := range sig.Args {
+		a := &sig.Args[i]
+		a.Index = i
+		if a.Name, err = k.ArgName(i); err != nil {
+			return nil, err
+		}
+		if a.TypeName, err = k.ArgTypeName(i); err != nil {
+			return nil, err
+		}
+		if a.Address, err = k.ArgAddress(i); err != nil {
+			return nil, err
+		}
+		if a.Access, err = k.ArgAccess(i); err != nil {
+			return nil, err
+		}
+		if a.Qualifiers, err = k.ArgTypeQualifier(i); err != nil {
+			return nil, err
+		}
+		a.Kind, a.BaseType, a.Width = parseArgType(a.TypeName, a.Qualifiers)
+	}
+	return sig, nil
+}
+
+// Sets the argument called name as SetArg would. The argument names are
+// looked up on first use, which needs a program built with
+// -cl-kernel-arg-info.
+func (k *Kernel) SetArgByName(name string, arg interface{}) error {
+	if k.argIndex == nil {
+		num, err := k.NumArgs()
+		if err != nil {
+			return err
+		}
+		index := make(map[string]int, num)
+		for i := 0; i < num; i++ {
+			argName, err := k.ArgName(i)
+			if err != nil {
+				return err
+			}
+			index[argName] = i
+		}
+		k.argIndex = index
+	}
+	i, ok := k.argIndex[name]
+	if !ok {
+		return ErrUnknownArgument{Kernel: k.name, Name: name}
+	}
+	return k.SetArg(i, arg)
+}
@@ -0,0 +1,29 @@
+package cl
+
+import "testing"
+
+func TestParseArgType(t *testing.T) {
+	cases := []struct {
+		typeName   string
+		qualifiers ArgTypeQualifier
+		kind       ArgKind
+		base       string
+		width      int
+	}{
+		{"float", ArgTypeNone, ArgKindScalar, "float", 1},
+		{"float4", ArgTypeNone, ArgKindVector, "float", 4},
+		{"float*", ArgTypeConst, ArgKindPointer, "float", 1},
+		{"uchar16*", ArgTypeNone, ArgKindPointer, "uchar", 16},
+		{"image2d_t", ArgTypeNone, ArgKindImage, "image2d_t", 1},
+		{"sampler_t", ArgTypeNone, ArgKindSampler, "sampler_t", 1},
+		{"int", ArgTypePipe, ArgKindPipe, "int", 1},
+		{"params", ArgTypeNone, ArgKindOther, "params", 1},
+		{"params*", ArgTypeNone, ArgKindPointer, "params", 1},
+	}
+	for _, c := range cases {
+		kind, base, width := parseArgType(c.typeName, c.qualifiers)
+		if kind != c.kind || base != c.base || width != c.width {
+			t.Errorf("%s: got %v %q %d, expected %v %q %d", c.typeName, kind, base, width, c.kind, c.base, c.width)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package cl
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+//////////////// Abstract Types ////////////////
+
+// Parameters a KernelTemplate is specialized with. Type, Width and Unroll
+// are available to the template as {{.Type}}, {{.Width}} and {{.Unroll}};
+// Values holds any further template parameters as {{.Values.name}}. Defines
+// are passed to the compiler as -D name=value, for kernels specialized with
+// macros instead of (or as well as) template actions.
+type KernelParams struct {
+	Type    string
+	Width   int
+	Unroll  int
+	Values  map[string]interface{}
+	Defines map[string]string
+}
+
+// An OpenCL C source template specialized with text/template. Each distinct
+// specialization is built once per context and device set; later requests
+// for the same parameters return the memoized program.
+//
+// Besides the standard template functions, templates can use
+//
+//	vec T N     the name of the N component vector of T ("float4"), or T if N is 1
+//	seq N       the integers 0 to N-1, for unrolling with range
+//	fp64 T      the cl_khr_fp64 pragma if T is double or a double vector
+type KernelTemplate struct {
+	tmpl     *template.Template
+	options  string
+	mu       sync.Mutex
+	programs map[templateKey]*templateBuild
+}
+
+type templateKey struct {
+	context *Context
+	devices string
+	source  string
+	options string
+}
+
+type templateBuild struct {
+	done    chan struct{}
+	program *Program
+	err     error
+}
+
+//////////////// Basic Functions ////////////////
+
+var templateFuncs = template.FuncMap{
+	"vec": func(t string, n int) string {
+		if n <= 1 {
+			return t
+		}
+		return t + strconv.Itoa(n)
+	},
+	"seq": func(n int) []int {
+		s := make([]int, n)
+		for i := range s {
+			s[i] = i
+		}
+		return s
+	},
+	"fp64": func(t string) string {
+		if strings.HasPrefix(t, "double") {
+			return "#pragma OPENCL EXTENSION cl_khr_fp64 : enable"
+		}
+		return ""
+	},
+}
+
+// Parses source as a template. options are used for every build, in
+// addition to the Defines of the parameters.
+func NewKernelTemplate(name, source, options string) (*KernelTemplate, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	return &KernelTemplate{tmpl: tmpl, options: options, programs: map[templateKey]*templateBuild{}}, nil
+}
+
+//////////////// Abstract Functions ////////////////
+
+// Returns the source specialized with params.
+func (t *KernelTemplate) Source(params KernelParams) (string, error) {
+	if params.Width == 0 {
+		params.Width = 1
+	}
+	if params.Unroll == 0 {
+		params.Unroll = 1
+	}
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, params); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Returns the build options for params: the options of the template followed
+// by the Defines, sorted by name.
+func (t *KernelTemplate) Options(params KernelParams) (string, error) {
+	names := make([]string, 0, len(params.Defines))
+	for name := range params.Defines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	opts := NewBuildOptions()
+	for _, name := range names {
+		opts.DefineValue(name, params.Defines[name])
+	}
+	defines, err := opts.BuildString()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(t.options + " " + defines), nil
+}
+
+// Returns the program specialized with params and built for devices (all
+// devices of the context if nil), building it on first use. Concurrent
+// requests for the same specialization wait for a single build. Failed
+// builds are not memoized, so a later call tries again.
+func (t *KernelTemplate) Build(ctx *Context, devices []*Device, params KernelParams) (*Program, error) {
+	if len(devices) == 0 {
+		devices = ctx.devices
+	}
+	source, err := t.Source(params)
+	if err != nil {
+		return nil, err
+	}
+	options, err := t.Options(params)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(devices))
+	for i, dev := range devices {
+		ids[i] = fmt.Sprintf("%p", dev.id)
+	}
+	key := templateKey{context: ctx, devices: strings.Join(ids, ","), source: source, options: options}
+
+	t.mu.Lock()
+	if b, ok := t.programs[key]; ok {
+		t.mu.Unlock()
+		<-b.done
+		return b.program, b.err
+	}
+	b := &templateBuild{done: make(chan struct{})}
+	t.programs[key] = b
+	t.mu.Unlock()
+
+	b.program, b.err = buildSource(ctx, devices, source, options)
+	if b.err != nil {
+		t.mu.Lock()
+		if t.programs[key] == b {
+			delete(t.programs, key)
+		}
+		t.mu.Unlock()
+	}
+	close(b.done)
+	return b.program, b.err
+}
+
+func buildSource(ctx *Context, devices []*Device, source, options string) (*Program, error) {
+	program, err := ctx.CreateProgramWithSource([]string{source})
+	if err != nil {
+		return nil, err
+	}
+	if err := program.BuildProgram(devices, options); err != nil {
+		program.Release()
+		return nil, err
+	}
+	return program, nil
+}
+
+// Releases every memoized program. Programs returned by Build must not be
+// used afterwards.
+func (t *KernelTemplate) Release() {
+	t.mu.Lock()
+	builds := t.programs
+	t.programs = map[templateKey]*templateBuild{}
+	t.mu.Unlock()
+	for _, b := range builds {
+		<-b.done
+		if b.program != nil {
+			b.program.Release()
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package cl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKernelTemplate(t *testing.T) {
+	tmpl, err := NewKernelTemplate("scale", `{{fp64 .Type}}
+__kernel void scale(__global {{vec .Type .Width}} *x, {{.Type}} a) {
+	int i = get_global_id(0) * {{.Unroll}};
+{{range seq .Unroll}}	x[i + {{.}}] *= a;
+{{end}}}`, "-cl-mad-enable")
+	if err != nil {
+		t.Fatalf("NewKernelTemplate failed: %+v", err)
+	}
+	src, err := tmpl.Source(KernelParams{Type: "double", Width: 4, Unroll: 2})
+	if err != nil {
+		t.Fatalf("Source failed: %+v", err)
+	}
+	for _, want := range []string{"cl_khr_fp64", "__global double4 *x, double a", "x[i + 1] *= a;"} {
+		if !strings.Contains(src, want) {
+			t.Errorf("specialized source lacks %q:\n%s", want, src)
+		}
+	}
+	src, _ = tmpl.Source(KernelParams{Type: "float"})
+	if strings.Contains(src, "cl_khr_fp64") || !strings.Contains(src, "__global float *x") || strings.Contains(src, "x[i + 1]") {
+		t.Errorf("unexpected defaults:\n%s", src)
+	}
+
+	opts, err := tmpl.Options(KernelParams{Defines: map[string]string{"TILE": "16", "ALPHA": "0.5f"}})
+	if err != nil || opts != "-cl-mad-enable -D ALPHA=0.5f -D TILE=16" {
+		t.Errorf("unexpected options %q: %v", opts, err)
+	}
+}
@@ -0,0 +1,327 @@
+package cl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrProfilingDisabled    = errors.New("cl: tuning needs a command queue created with CommandQueueProfilingEnable")
+	ErrNoValidConfiguration = errors.New("cl: no tuning candidate could be built and run")
+)
+
+//////////////// Abstract Types ////////////////
+
+// A configuration to benchmark: extra build options, such as "-D TILE=16",
+// and a local work size. A nil Local lets Kernel.LaunchConfig choose one.
+type TuneCandidate struct {
+	Options string
+	Local   []int
+}
+
+// The fastest configuration found for a kernel and problem size on a
+// device. Config identifies what was tuned, see Tuner.Config. Global is
+// Extent padded to a multiple of Local; Time is the fastest of the timed
+// runs.
+type TuneResult struct {
+	Kernel  string        `json:"kernel"`
+	Config  string        `json:"config"`
+	Extent  []int         `json:"extent"`
+	Global  []int         `json:"global"`
+	Local   []int         `json:"local"`
+	Options string        `json:"options"`
+	Time    time.Duration `json:"time_ns"`
+}
+
+// Benchmarks a kernel over a set of candidate configurations and keeps the
+// fastest. SetArgs is called on every kernel built before it is run, with
+// the extent of the problem, and must set all its arguments. If Store is
+// set, results are looked up there first and saved there after tuning.
+type Tuner struct {
+	Sources    []string
+	Kernel     string
+	Options    string
+	Candidates []TuneCandidate
+	Runs       int // timed runs per candidate, after one warm-up run (3 if zero)
+	SetArgs    func(k *Kernel, extent []int) error
+	Store      *TuningStore
+}
+
+// Tuning results persisted as JSON, keyed by device name and driver version
+// and then by kernel, tuner configuration and problem size.
+type TuningStore struct {
+	path    string
+	mu      sync.Mutex
+	devices map[string]map[string]TuneResult
+}
+
+type tuningFile struct {
+	Devices map[string]map[string]TuneResult `json:"devices"`
+}
+
+//////////////// Basic Functions ////////////////
+
+// Returns every combination of the build option variants and local sizes.
+func TuneCandidates(options []string, locals [][]int) []TuneCandidate {
+	if len(options) == 0 {
+		options = []string{""}
+	}
+	if len(locals) == 0 {
+		locals = [][]int{nil}
+	}
+	candidates := make([]TuneCandidate, 0, len(options)*len(locals))
+	for _, opt := range options {
+		for _, local := range locals {
+			candidates = append(candidates, TuneCandidate{Options: opt, Local: local})
+		}
+	}
+	return candidates
+}
+
+func tuningDeviceKey(device *Device) string {
+	return device.Name() + " / " + device.DriverVersion()
+}
+
+func tuningProblemKey(kernel, config string, extent []int) string {
+	dims := make([]string, len(extent))
+	for i, e := range extent {
+		dims[i] = strconv.Itoa(e)
+	}
+	return kernel + " " + config + " " + strings.Join(dims, "x")
+}
+
+// Identifies the sources, base options and candidates of the tuner, so that
+// stored results are not reused once any of them changes.
+func (t *Tuner) Config() string {
+	h := sha256.New()
+	for _, src := range t.Sources {
+		hashString(h, src)
+	}
+	hashString(h, t.Options)
+	for _, c := range t.Candidates {
+		hashString(h, c.Options)
+		hashString(h, fmt.Sprint(c.Local))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// Opens the store kept in the file at path. A missing file gives an empty
+// store, created on the first Save.
+func OpenTuningStore(path string) (*TuningStore, error) {
+	s := &TuningStore{path: path, devices: map[string]map[string]TuneResult{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f tuningFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("cl: reading tuning store %s: %w", path, err)
+	}
+	if f.Devices != nil {
+		s.devices = f.Devices
+	}
+	return s, nil
+}
+
+// The result stored for the kernel, tuner configuration and problem extent
+// on device.
+func (s *TuningStore) Lookup(device *Device, kernel, config string, extent []int) (TuneResult, bool) {
+	return s.lookup(tuningDeviceKey(device), kernel, config, extent)
+}
+
+func (s *TuningStore) lookup(key, kernel, config string, extent []int) (TuneResult, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.devices[key][tuningProblemKey(kernel, config, extent)]
+	return r, ok
+}
+
+// Records result for device and writes the store to its file.
+func (s *TuningStore) Save(device *Device, result TuneResult) error {
+	return s.save(tuningDeviceKey(device), result)
+}
+
+func (s *TuningStore) save(key string, result TuneResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.devices[key] == nil {
+		s.devices[key] = map[string]TuneResult{}
+	}
+	s.devices[key][tuningProblemKey(result.Kernel, result.Config, result.Extent)] = result
+
+	data, err := json.MarshalIndent(tuningFile{Devices: s.devices}, "", "\t")
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first so readers never see a partial store
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+//////////////// Abstract Functions ////////////////
+
+// Returns the stored result for the problem extent on the device of q, or
+// tunes the kernel with Tune if there is none for the current sources,
+// options and candidates.
+func (t *Tuner) Best(ctx *Context, q *CommandQueue, extent []int) (TuneResult, error) {
+	if t.Store != nil {
+		device, err := queueDevice(q)
+		if err != nil {
+			return TuneResult{}, err
+		}
+		if r, ok := t.Store.Lookup(device, t.Kernel, t.Config(), extent); ok {
+			return r, nil
+		}
+	}
+	return t.Tune(ctx, q, extent)
+}
+
+// Builds and runs every candidate over extent on the device of q, which
+// must have profiling enabled, and returns the fastest. Candidates that fail
+// to build or launch, for instance with a local size the device rejects,
+// are skipped. The result is saved to Store if set.
+func (t *Tuner) Tune(ctx *Context, q *CommandQueue, extent []int) (TuneResult, error) {
+	props, err := q.GetQueueProperties()
+	if err != nil {
+		return TuneResult{}, err
+	}
+	if props&CommandQueueProfilingEnable == 0 {
+		return TuneResult{}, ErrProfilingDisabled
+	}
+	device, err := queueDevice(q)
+	if err != nil {
+		return TuneResult{}, err
+	}
+	candidates := t.Candidates
+	if len(candidates) == 0 {
+		candidates = []TuneCandidate{{}}
+	}
+	runs := t.Runs
+	if runs <= 0 {
+		runs = 3
+	}
+	config := t.Config()
+
+	// Build each option variant once and time all its local sizes
+	var best *TuneResult
+	kernels := map[string]*Kernel{}
+	failed := map[string]bool{}
+	defer func() {
+		for _, k := range kernels {
+			k.Release()
+		}
+	}()
+	for _, c := range candidates {
+		options := strings.TrimSpace(t.Options + " " + c.Options)
+		if failed[options] {
+			continue
+		}
+		k, ok := kernels[options]
+		if !ok {
+			if k, err = t.buildKernel(ctx, device, options); err != nil {
+				failed[options] = true
+				continue
+			}
+			if t.SetArgs != nil {
+				if err := t.SetArgs(k, extent); err != nil {
+					k.Release()
+					return TuneResult{}, err
+				}
+			}
+			kernels[options] = k
+		}
+		local := c.Local
+		if local == nil {
+			config, err := k.LaunchConfig(device, extent)
+			if err != nil {
+				continue
+			}
+			local = config.Local
+		}
+		if len(local) != len(extent) {
+			continue
+		}
+		global := padGlobalSize(extent, local)
+		elapsed, err := timeKernel(q, k, global, local, runs)
+		if err != nil {
+			continue
+		}
+		if best == nil || elapsed < best.Time {
+			best = &TuneResult{Kernel: t.Kernel, Config: config, Extent: append([]int(nil), extent...), Global: global, Local: append([]int(nil), local...), Options: options, Time: elapsed}
+		}
+	}
+	if best == nil {
+		return TuneResult{}, ErrNoValidConfiguration
+	}
+	if t.Store != nil {
+		if err := t.Store.Save(device, *best); err != nil {
+			return *best, err
+		}
+	}
+	return *best, nil
+}
+
+func (t *Tuner) buildKernel(ctx *Context, device *Device, options string) (*Kernel, error) {
+	program, err := ctx.CreateProgramWithSource(t.Sources)
+	if err != nil {
+		return nil, err
+	}
+	// The kernel keeps its program alive
+	defer program.Release()
+	if err := program.BuildProgram([]*Device{device}, options); err != nil {
+		return nil, err
+	}
+	return program.CreateKernel(t.Kernel)
+}
+
+// Runs the kernel once to warm up, then runs times, and returns the fastest
+// run as measured by event profiling.
+func timeKernel(q *CommandQueue, k *Kernel, global, local []int, runs int) (time.Duration, error) {
+	var fastest time.Duration
+	for i := 0; i <= runs; i++ {
+		event, err := q.EnqueueNDRangeKernel(k, nil, global, local, nil)
+		if err != nil {
+			return 0, err
+		}
+		err = WaitForEvents([]*Event{event})
+		var start, end int64
+		if err == nil {
+			start, err = event.GetEventProfilingInfo(ProfilingInfoCommandStart)
+		}
+		if err == nil {
+			end, err = event.GetEventProfilingInfo(ProfilingInfoCommandEnd)
+		}
+		event.Release()
+		if err != nil {
+			return 0, err
+		}
+		if elapsed := time.Duration(end - start); i > 0 && (i == 1 || elapsed < fastest) {
+			fastest = elapsed
+		}
+	}
+	return fastest, nil
+}
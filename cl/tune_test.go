@@ -0,0 +1,65 @@
+package cl
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTuningStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tuning.json")
+	s, err := OpenTuningStore(path)
+	if err != nil {
+		t.Fatalf("OpenTuningStore failed on a missing file: %+v", err)
+	}
+	result := TuneResult{Kernel: "matmul", Config: "0123456789abcdef", Extent: []int{1000, 1000}, Global: []int{1008, 1008}, Local: []int{16, 16}, Options: "-D TILE=16", Time: 3 * time.Millisecond}
+	if err := s.save("GPU / 1.0", result); err != nil {
+		t.Fatalf("save failed: %+v", err)
+	}
+
+	s, err = OpenTuningStore(path)
+	if err != nil {
+		t.Fatalf("OpenTuningStore failed: %+v", err)
+	}
+	got, ok := s.lookup("GPU / 1.0", "matmul", "0123456789abcdef", []int{1000, 1000})
+	if !ok || !reflect.DeepEqual(got, result) {
+		t.Errorf("expected %+v, got %+v (found %v)", result, got, ok)
+	}
+	if _, ok := s.lookup("GPU / 2.0", "matmul", "0123456789abcdef", []int{1000, 1000}); ok {
+		t.Errorf("found a result for another driver version")
+	}
+	if _, ok := s.lookup("GPU / 1.0", "matmul", "0123456789abcdef", []int{1000, 500}); ok {
+		t.Errorf("found a result for another problem size")
+	}
+	if _, ok := s.lookup("GPU / 1.0", "matmul", "fedcba9876543210", []int{1000, 1000}); ok {
+		t.Errorf("found a result for another tuner configuration")
+	}
+}
+
+func TestTunerConfig(t *testing.T) {
+	base := Tuner{Sources: []string{"__kernel void matmul() {}"}, Kernel: "matmul", Options: "-cl-fast-relaxed-math", Candidates: TuneCandidates([]string{"-D TILE=8"}, [][]int{{8, 8}})}
+	config := base.Config()
+	if again := base.Config(); again != config {
+		t.Fatalf("config not stable: %s and %s", config, again)
+	}
+	changes := map[string]func(t *Tuner){
+		"sources":    func(t *Tuner) { t.Sources = []string{"__kernel void matmul(int n) {}"} },
+		"options":    func(t *Tuner) { t.Options = "" },
+		"candidates": func(t *Tuner) { t.Candidates = TuneCandidates([]string{"-D TILE=8"}, [][]int{{16, 16}}) },
+	}
+	for what, change := range changes {
+		tuner := base
+		change(&tuner)
+		if tuner.Config() == config {
+			t.Errorf("config unchanged after changing the %s", what)
+		}
+	}
+}
+
+func TestTuneCandidates(t *testing.T) {
+	c := TuneCandidates([]string{"-D TILE=8", "-D TILE=16"}, [][]int{{8, 8}, {16, 16}, nil})
+	if len(c) != 6 || c[0].Options != "-D TILE=8" || c[5].Options != "-D TILE=16" || c[5].Local != nil {
+		t.Errorf("unexpected candidates %+v", c)
+	}
+}
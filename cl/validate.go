@@ -0,0 +1,220 @@
+package cl
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+//////////////// Basic Types ////////////////
+
+// Returned by SetArg on a kernel with argument validation enabled when a Go
+// value does not match the declared type of the argument.
+type ErrArgumentMismatch struct {
+	Kernel   string
+	Index    int
+	Name     string
+	Expected string
+	Value    interface{}
+	Reason   string
+}
+
+func (e ErrArgumentMismatch) Error() string {
+	return fmt.Sprintf("cl: kernel %s argument %d (%s): expected %s, got %T: %s", e.Kernel, e.Index, e.Name, e.Expected, e.Value, e.Reason)
+}
+
+//////////////// Abstract Types ////////////////
+
+// Declared address qualifier, name and type of a kernel argument, as
+// reported for programs built with -cl-kernel-arg-info.
+type kernelArgInfo struct {
+	address  string
+	name     string
+	typeName string
+}
+
+// Implemented by every typed Buffer.
+type typedBufferArg interface {
+	elementType() reflect.Type
+}
+
+//////////////// Basic Functions ////////////////
+
+var scalarNames = map[reflect.Kind]string{
+	reflect.Int8:    "char",
+	reflect.Uint8:   "uchar",
+	reflect.Int16:   "short",
+	reflect.Uint16:  "ushort",
+	reflect.Int32:   "int",
+	reflect.Uint32:  "uint",
+	reflect.Int64:   "long",
+	reflect.Uint64:  "ulong",
+	reflect.Float32: "float",
+	reflect.Float64: "double",
+}
+
+// The OpenCL C scalar or vector type name of t, or "" if t is neither.
+func clTypeName(t reflect.Type) string {
+	if vectorTypes[t] {
+		return strings.ToLower(t.Name())
+	}
+	if isVectorType(t) {
+		return clTypeName(t.Elem()) + strconv.Itoa(t.Len())
+	}
+	switch t.Kind() {
+	case reflect.Int:
+		if t.Size() == 8 {
+			return "long"
+		}
+		return "int"
+	case reflect.Uint, reflect.Uintptr:
+		if t.Size() == 8 {
+			return "ulong"
+		}
+		return "uint"
+	}
+	return scalarNames[t.Kind()]
+}
+
+// Whether name is an OpenCL C scalar or vector type, whose values must
+// match exactly.
+func isBuiltinTypeName(name string) bool {
+	base := strings.TrimRight(name, "0123456789")
+	switch base {
+	case "char", "uchar", "short", "ushort", "int", "uint", "long", "ulong", "half", "float", "double":
+		return true
+	}
+	return false
+}
+
+// Whether a Go value of OpenCL C type name can be used for declared, which
+// also allows ushort for half since Go has no half type.
+func sameType(name, declared string) bool {
+	return name == declared || strings.HasPrefix(declared, "half") && name == "ushort"+strings.TrimPrefix(declared, "half")
+}
+
+// Checks arg against the declared argument info, returning why it does not
+// match or "" if it does. Types the package cannot check, such as structs
+// declared by the program, are accepted.
+func checkArg(info kernelArgInfo, arg interface{}) string {
+	switch info.address {
+	case "Global", "Constant":
+		var elem reflect.Type
+		switch val := arg.(type) {
+		case typedBufferArg:
+			elem = val.elementType()
+		case *MemObject, memObjectArg:
+		default:
+			return "a pointer argument needs a buffer"
+		}
+		pointee := strings.TrimSuffix(info.typeName, "*")
+		if elem != nil && isBuiltinTypeName(pointee) && !sameType(clTypeName(elem), pointee) {
+			return fmt.Sprintf("buffer holds %s elements", clTypeName(elem))
+		}
+		return ""
+	case "Local":
+		if _, ok := arg.(LocalBuffer); !ok {
+			return "a __local argument needs a LocalBuffer"
+		}
+		return ""
+	}
+
+	switch arg.(type) {
+	case *MemObject, memObjectArg:
+		if strings.HasPrefix(info.typeName, "image") {
+			return ""
+		}
+		return "a buffer can only be passed to a pointer or image argument"
+	case LocalBuffer:
+		return "a LocalBuffer can only be passed to a __local argument"
+	case *Sampler:
+		if info.typeName != "sampler_t" {
+			return "a sampler can only be passed to a sampler_t argument"
+		}
+		return ""
+	}
+	if info.typeName == "sampler_t" || strings.HasPrefix(info.typeName, "image") {
+		return "the argument needs a memory object or sampler"
+	}
+
+	t := reflect.TypeOf(arg)
+	if t == nil {
+		return "nil value"
+	}
+	if t.Kind() == reflect.Bool {
+		// Passed as an int holding 0 or 1
+		if info.typeName != "int" && info.typeName != "uint" && info.typeName != "bool" {
+			return "bool is passed as an int"
+		}
+		return ""
+	}
+	if !isBuiltinTypeName(info.typeName) {
+		return ""
+	}
+	if name := clTypeName(t); !sameType(name, info.typeName) {
+		if name == "" {
+			return fmt.Sprintf("%s values cannot be passed as %s", t.Kind(), info.typeName)
+		}
+		return fmt.Sprintf("a Go %s is passed as %s", t, name)
+	}
+	return ""
+}
+
+// The declared type of the argument as written in OpenCL C, e.g.
+// "__global float*".
+func (info kernelArgInfo) declaration() string {
+	switch info.address {
+	case "Global":
+		return "__global " + info.typeName
+	case "Constant":
+		return "__constant " + info.typeName
+	case "Local":
+		return "__local " + info.typeName
+	}
+	return info.typeName
+}
+
+//////////////// Abstract Functions ////////////////
+
+// Enables or disables the checking of the values passed to SetArg and
+// SetArgs against the declared argument types. The program must have been
+// built with -cl-kernel-arg-info (see BuildOptions.KernelArgInfo), otherwise
+// enabling fails with ErrKernelArgInfoNotAvailable. Mismatches are reported
+// as ErrArgumentMismatch; the typed setters such as SetArgFloat32 are not
+// checked.
+func (k *Kernel) SetArgValidation(enabled bool) error {
+	if !enabled {
+		k.argInfo = nil
+		return nil
+	}
+	num, err := k.NumArgs()
+	if err != nil {
+		return err
+	}
+	infos := make([]kernelArgInfo, num)
+	for i := range infos {
+		if infos[i].address, err = k.ArgAddressQualifier(i); err != nil {
+			return err
+		}
+		if infos[i].name, err = k.ArgName(i); err != nil {
+			return err
+		}
+		if infos[i].typeName, err = k.ArgTypeName(i); err != nil {
+			return err
+		}
+	}
+	k.argInfo = infos
+	return nil
+}
+
+func (k *Kernel) validateArg(index int, arg interface{}) error {
+	if index < 0 || index >= len(k.argInfo) {
+		return ErrInvalidArgIndex
+	}
+	info := k.argInfo[index]
+	if reason := checkArg(info, arg); reason != "" {
+		return ErrArgumentMismatch{Kernel: k.name, Index: index, Name: info.name, Expected: info.declaration(), Value: arg, Reason: reason}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package cl
+
+import "testing"
+
+func TestCheckArg(t *testing.T) {
+	floats := &Buffer[float32]{MemObject: &MemObject{}}
+	cases := []struct {
+		info kernelArgInfo
+		arg  interface{}
+		ok   bool
+	}{
+		{kernelArgInfo{address: "Global", typeName: "float*"}, floats, true},
+		{kernelArgInfo{address: "Global", typeName: "float*"}, float32(1), false},
+		{kernelArgInfo{address: "Global", typeName: "int*"}, floats, false},
+		{kernelArgInfo{address: "Global", typeName: "mystruct*"}, floats, true},
+		{kernelArgInfo{address: "Constant", typeName: "float*"}, &MemObject{}, true},
+		{kernelArgInfo{address: "Local", typeName: "float*"}, LocalBuffer(64), true},
+		{kernelArgInfo{address: "Local", typeName: "float*"}, floats, false},
+		{kernelArgInfo{address: "Private", typeName: "double"}, float64(1), true},
+		{kernelArgInfo{address: "Private", typeName: "double"}, int32(1), false},
+		{kernelArgInfo{address: "Private", typeName: "float4"}, Float4{}, true},
+		{kernelArgInfo{address: "Private", typeName: "float3"}, [3]float32{}, true},
+		{kernelArgInfo{address: "Private", typeName: "float4"}, Int4{}, false},
+		{kernelArgInfo{address: "Private", typeName: "half"}, uint16(0), true},
+		{kernelArgInfo{address: "Private", typeName: "int"}, true, true},
+		{kernelArgInfo{address: "Private", typeName: "params"}, struct{ A int32 }{}, true},
+		{kernelArgInfo{address: "Private", typeName: "sampler_t"}, int32(0), false},
+	}
+	for i, c := range cases {
+		reason := checkArg(c.info, c.arg)
+		if (reason == "") != c.ok {
+			t.Errorf("case %d: %s with %T: got %q", i, c.info.declaration(), c.arg, reason)
+		}
+	}
+}
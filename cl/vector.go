@@ -0,0 +1,138 @@
+package cl
+
+import "reflect"
+
+//////////////// Golang Types ////////////////
+
+// Go counterparts of the OpenCL C vector types. Each type has the size and
+// lane order of the matching cl_<type>n host type, so values can be used as
+// kernel arguments, typed Buffer elements and fill patterns. The 3-component
+// types occupy four lanes like cl_<type>3; the fourth lane is padding and is
+// left as zero by a composite literal such as Float3{1, 2, 3}. Half vectors
+// hold the raw IEEE 754 binary16 bits of each lane.
+type (
+	Char2  [2]int8  // char2
+	Char3  [4]int8  // char3
+	Char4  [4]int8  // char4
+	Char8  [8]int8  // char8
+	Char16 [16]int8 // char16
+)
+
+type (
+	Uchar2  [2]uint8  // uchar2
+	Uchar3  [4]uint8  // uchar3
+	Uchar4  [4]uint8  // uchar4
+	Uchar8  [8]uint8  // uchar8
+	Uchar16 [16]uint8 // uchar16
+)
+
+type (
+	Short2  [2]int16  // short2
+	Short3  [4]int16  // short3
+	Short4  [4]int16  // short4
+	Short8  [8]int16  // short8
+	Short16 [16]int16 // short16
+)
+
+type (
+	Ushort2  [2]uint16  // ushort2
+	Ushort3  [4]uint16  // ushort3
+	Ushort4  [4]uint16  // ushort4
+	Ushort8  [8]uint16  // ushort8
+	Ushort16 [16]uint16 // ushort16
+)
+
+type (
+	Int2  [2]int32  // int2
+	Int3  [4]int32  // int3
+	Int4  [4]int32  // int4
+	Int8  [8]int32  // int8
+	Int16 [16]int32 // int16
+)
+
+type (
+	Uint2  [2]uint32  // uint2
+	Uint3  [4]uint32  // uint3
+	Uint4  [4]uint32  // uint4
+	Uint8  [8]uint32  // uint8
+	Uint16 [16]uint32 // uint16
+)
+
+type (
+	Long2  [2]int64  // long2
+	Long3  [4]int64  // long3
+	Long4  [4]int64  // long4
+	Long8  [8]int64  // long8
+	Long16 [16]int64 // long16
+)
+
+type (
+	Ulong2  [2]uint64  // ulong2
+	Ulong3  [4]uint64  // ulong3
+	Ulong4  [4]uint64  // ulong4
+	Ulong8  [8]uint64  // ulong8
+	Ulong16 [16]uint64 // ulong16
+)
+
+type (
+	Half2  [2]uint16  // half2
+	Half3  [4]uint16  // half3
+	Half4  [4]uint16  // half4
+	Half8  [8]uint16  // half8
+	Half16 [16]uint16 // half16
+)
+
+type (
+	Float2  [2]float32  // float2
+	Float3  [4]float32  // float3
+	Float4  [4]float32  // float4
+	Float8  [8]float32  // float8
+	Float16 [16]float32 // float16
+)
+
+type (
+	Double2  [2]float64  // double2
+	Double3  [4]float64  // double3
+	Double4  [4]float64  // double4
+	Double8  [8]float64  // double8
+	Double16 [16]float64 // double16
+)
+
+// Vector is the set of Go vector types above.
+type Vector interface {
+	Char2 | Char3 | Char4 | Char8 | Char16 |
+		Uchar2 | Uchar3 | Uchar4 | Uchar8 | Uchar16 |
+		Short2 | Short3 | Short4 | Short8 | Short16 |
+		Ushort2 | Ushort3 | Ushort4 | Ushort8 | Ushort16 |
+		Int2 | Int3 | Int4 | Int8 | Int16 |
+		Uint2 | Uint3 | Uint4 | Uint8 | Uint16 |
+		Long2 | Long3 | Long4 | Long8 | Long16 |
+		Ulong2 | Ulong3 | Ulong4 | Ulong8 | Ulong16 |
+		Half2 | Half3 | Half4 | Half8 | Half16 |
+		Float2 | Float3 | Float4 | Float8 | Float16 |
+		Double2 | Double3 | Double4 | Double8 | Double16
+}
+
+// Used by the kernel argument layout to give vector members of a struct
+// vector alignment instead of array alignment.
+var vectorTypes = map[reflect.Type]bool{}
+
+//////////////// Basic Functions ////////////////
+
+func init() {
+	for _, v := range []interface{}{
+		Char2{}, Char3{}, Char4{}, Char8{}, Char16{},
+		Uchar2{}, Uchar3{}, Uchar4{}, Uchar8{}, Uchar16{},
+		Short2{}, Short3{}, Short4{}, Short8{}, Short16{},
+		Ushort2{}, Ushort3{}, Ushort4{}, Ushort8{}, Ushort16{},
+		Int2{}, Int3{}, Int4{}, Int8{}, Int16{},
+		Uint2{}, Uint3{}, Uint4{}, Uint8{}, Uint16{},
+		Long2{}, Long3{}, Long4{}, Long8{}, Long16{},
+		Ulong2{}, Ulong3{}, Ulong4{}, Ulong8{}, Ulong16{},
+		Half2{}, Half3{}, Half4{}, Half8{}, Half16{},
+		Float2{}, Float3{}, Float4{}, Float8{}, Float16{},
+		Double2{}, Double3{}, Double4{}, Double8{}, Double16{},
+	} {
+		vectorTypes[reflect.TypeOf(v)] = true
+	}
+}
@@ -0,0 +1,194 @@
+// Command clembed builds OpenCL C sources for the devices of this machine
+// and writes a Go file embedding the resulting binaries, labelled with the
+// device and driver that produced them, together with the sources. The
+// generated variable is a *cl.EmbeddedProgram whose Load method uses a
+// matching binary and falls back to compiling the sources otherwise.
+//
+// Usage:
+//
+//	//go:generate go run github.com/xfong/go2opencl/cmd/clembed -var Kernels -device "GTX 1080" -o kernels_bin.go vecadd.cl
+//
+// Unlike clgen it needs an OpenCL driver for every targeted device, so the
+// generated file is normally committed rather than regenerated on every
+// build.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/xfong/go2opencl/cl"
+)
+
+func main() {
+	output := flag.String("o", "", "output file (default: <first input>_bin.go)")
+	pkg := flag.String("pkg", os.Getenv("GOPACKAGE"), "package name of the generated file (default: $GOPACKAGE)")
+	varName := flag.String("var", "Program", "name of the generated *cl.EmbeddedProgram variable")
+	options := flag.String("options", "", "build options passed to BuildProgram")
+	platformName := flag.String("platform", "", "only use platforms whose name contains this string")
+	deviceNames := flag.String("device", "", "comma separated substrings; only build for devices whose name contains one of them")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: clembed [flags] file.cl...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *pkg == "" {
+		fatalf("no package name; use -pkg or run through go generate")
+	}
+	files := flag.Args()
+	if *output == "" {
+		*output = strings.TrimSuffix(files[0], filepath.Ext(files[0])) + "_bin.go"
+	}
+
+	sources := make([]string, len(files))
+	for i, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			fatalf("%v", err)
+		}
+		sources[i] = string(data)
+	}
+
+	var filters []string
+	if *deviceNames != "" {
+		filters = strings.Split(*deviceNames, ",")
+	}
+	binaries, err := buildAll(sources, *options, *platformName, filters)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	if len(binaries) == 0 {
+		fatalf("no matching OpenCL device found")
+	}
+	out, err := generate(*pkg, *varName, files, sources, *options, binaries)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	if err := os.WriteFile(*output, out, 0644); err != nil {
+		fatalf("%v", err)
+	}
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "clembed: "+format+"\n", args...)
+	os.Exit(1)
+}
+
+func selected(name string, filters []string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, f := range filters {
+		if strings.Contains(name, strings.TrimSpace(f)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Builds sources on every selected device, one context per platform, and
+// returns their binaries.
+func buildAll(sources []string, options, platformName string, filters []string) ([]cl.DeviceBinary, error) {
+	platforms, err := cl.GetPlatforms()
+	if err != nil {
+		return nil, err
+	}
+	var binaries []cl.DeviceBinary
+	for _, platform := range platforms {
+		if !strings.Contains(platform.Name(), platformName) {
+			continue
+		}
+		all, err := platform.GetDevices(cl.DeviceTypeAll)
+		if err != nil {
+			return nil, err
+		}
+		var devices []*cl.Device
+		for _, dev := range all {
+			if selected(dev.Name(), filters) {
+				devices = append(devices, dev)
+			}
+		}
+		if len(devices) == 0 {
+			continue
+		}
+		ctx, err := cl.CreateContext(devices)
+		if err != nil {
+			return nil, err
+		}
+		program, err := ctx.CreateProgramWithSource(sources)
+		if err != nil {
+			return nil, err
+		}
+		if err := program.BuildProgram(devices, options); err != nil {
+			return nil, err
+		}
+		bins, err := program.ExportBinaries()
+		if err != nil {
+			return nil, err
+		}
+		binaries = append(binaries, bins...)
+		program.Release()
+		ctx.Release()
+	}
+	return binaries, nil
+}
+
+// Writes data as a single Go string literal. Splitting it into concatenated
+// pieces would nest one binary expression per piece, which gofmt formats in
+// quadratic time and rejects past its nesting limit for binaries of a few
+// megabytes.
+func writeBytes(b *bytes.Buffer, data []byte) {
+	const hex = "0123456789abcdef"
+	b.Grow(4*len(data) + 2)
+	// Hex escapes keep the output independent of the binary contents
+	b.WriteByte('"')
+	for _, c := range data {
+		b.WriteString(`\x`)
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0xf])
+	}
+	b.WriteByte('"')
+}
+
+func generate(pkg, varName string, files, sources []string, options string, binaries []cl.DeviceBinary) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by clembed from %s; DO NOT EDIT.\n\n", strings.Join(files, ", "))
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	fmt.Fprintf(&b, "import \"github.com/xfong/go2opencl/cl\"\n\n")
+	fmt.Fprintf(&b, "// %s holds %s with binaries precompiled for:\n", varName, strings.Join(files, ", "))
+	for _, bin := range binaries {
+		fmt.Fprintf(&b, "//   - %s (%s, driver %s)\n", bin.DeviceName, bin.PlatformVersion, bin.DriverVersion)
+	}
+	fmt.Fprintf(&b, "var %s = &cl.EmbeddedProgram{\n", varName)
+	fmt.Fprintf(&b, "\tSources: []string{\n")
+	for _, src := range sources {
+		fmt.Fprintf(&b, "\t\t%s,\n", strconv.Quote(src))
+	}
+	fmt.Fprintf(&b, "\t},\n")
+	fmt.Fprintf(&b, "\tOptions: %s,\n", strconv.Quote(options))
+	fmt.Fprintf(&b, "\tBinaries: []cl.DeviceBinary{\n")
+	for _, bin := range binaries {
+		fmt.Fprintf(&b, "\t\t{\n")
+		fmt.Fprintf(&b, "\t\t\tDeviceName: %s,\n", strconv.Quote(bin.DeviceName))
+		fmt.Fprintf(&b, "\t\t\tDeviceVendor: %s,\n", strconv.Quote(bin.DeviceVendor))
+		fmt.Fprintf(&b, "\t\t\tDeviceVersion: %s,\n", strconv.Quote(bin.DeviceVersion))
+		fmt.Fprintf(&b, "\t\t\tDriverVersion: %s,\n", strconv.Quote(bin.DriverVersion))
+		fmt.Fprintf(&b, "\t\t\tPlatformVersion: %s,\n", strconv.Quote(bin.PlatformVersion))
+		fmt.Fprintf(&b, "\t\t\tBinary: []byte(")
+		writeBytes(&b, bin.Binary)
+		fmt.Fprintf(&b, "),\n")
+		fmt.Fprintf(&b, "\t\t},\n")
+	}
+	fmt.Fprintf(&b, "\t},\n}\n")
+	return format.Source(b.Bytes())
+}
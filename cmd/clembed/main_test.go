@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/xfong/go2opencl/cl"
+)
+
+func TestGenerate(t *testing.T) {
+	bins := []cl.DeviceBinary{{DeviceName: "GPU \"X\"", DriverVersion: "1.0", Binary: make([]byte, 70)}}
+	out, err := generate("kernels", "Kernels", []string{"a.cl"}, []string{"__kernel void f() {}\n"}, "-cl-fast-relaxed-math", bins)
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "out.go", out, 0); err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, out)
+	}
+	for _, want := range []string{"var Kernels = &cl.EmbeddedProgram{", `DeviceName:      "GPU \"X\""`, `Options: "-cl-fast-relaxed-math"`} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("generated code lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+// Stands in for package cl when type checking generated code, which cannot
+// import the real package without cgo.
+const clStub = `package cl
+
+type DeviceBinary struct {
+	DeviceName, DeviceVendor, DeviceVersion, DriverVersion, PlatformVersion string
+	Binary []byte
+}
+
+type EmbeddedProgram struct {
+	Sources  []string
+	Options  string
+	Binaries []DeviceBinary
+}
+`
+
+type stubImporter map[string]*types.Package
+
+func (s stubImporter) Import(path string) (*types.Package, error) {
+	return s[path], nil
+}
+
+// Type checks generated code against clStub.
+func typeCheck(t *testing.T, fset *token.FileSet, file *ast.File) {
+	t.Helper()
+	stub, err := parser.ParseFile(fset, "cl.go", clStub, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clPkg, err := new(types.Config).Check("github.com/xfong/go2opencl/cl", fset, []*ast.File{stub}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: stubImporter{"github.com/xfong/go2opencl/cl": clPkg}}
+	if _, err := conf.Check("kernels", fset, []*ast.File{file}, nil); err != nil {
+		t.Fatalf("generated code does not compile: %v", err)
+	}
+}
+
+func TestGenerateLargeBinary(t *testing.T) {
+	binary := make([]byte, 4<<20)
+	for i := range binary {
+		binary[i] = byte(i * 7)
+	}
+	bins := []cl.DeviceBinary{{DeviceName: "GPU", Binary: binary}, {DeviceName: "CPU"}}
+	out, err := generate("kernels", "Kernels", []string{"a.cl"}, []string{"__kernel void f() {}\n"}, "", bins)
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "out.go", out, 0)
+	if err != nil {
+		t.Fatalf("generated code does not parse: %v", err)
+	}
+	typeCheck(t, fset, file)
+
+	var literals [][]byte
+	ast.Inspect(file, func(n ast.Node) bool {
+		if kv, ok := n.(*ast.KeyValueExpr); ok {
+			if key, ok := kv.Key.(*ast.Ident); ok && key.Name == "Binary" {
+				lit := kv.Value.(*ast.CallExpr).Args[0].(*ast.BasicLit)
+				s, err := strconv.Unquote(lit.Value)
+				if err != nil {
+					t.Fatalf("binary literal does not unquote: %v", err)
+				}
+				literals = append(literals, []byte(s))
+			}
+		}
+		return true
+	})
+	if len(literals) != 2 || !bytes.Equal(literals[0], binary) || len(literals[1]) != 0 {
+		t.Errorf("embedded binaries differ from the input")
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"strings"
+	"unicode"
+)
+
+// Go types of the OpenCL C scalar types.
+var scalarTypes = map[string]string{
+	"char":           "int8",
+	"signed char":    "int8",
+	"uchar":          "uint8",
+	"unsigned char":  "uint8",
+	"short":          "int16",
+	"signed short":   "int16",
+	"ushort":         "uint16",
+	"unsigned short": "uint16",
+	"int":            "int32",
+	"signed int":     "int32",
+	"signed":         "int32",
+	"uint":           "uint32",
+	"unsigned int":   "uint32",
+	"unsigned":       "uint32",
+	"long":           "int64",
+	"signed long":    "int64",
+	"ulong":          "uint64",
+	"unsigned long":  "uint64",
+	"half":           "uint16",
+	"float":          "float32",
+	"double":         "float64",
+}
+
+// Prefixes of the vector type names in package cl.
+var vectorPrefixes = map[string]string{
+	"char": "Char", "uchar": "Uchar", "short": "Short", "ushort": "Ushort",
+	"int": "Int", "uint": "Uint", "long": "Long", "ulong": "Ulong",
+	"half": "Half", "float": "Float", "double": "Double",
+}
+
+var imageTypes = map[string]bool{
+	"image1d_t": true, "image1d_buffer_t": true, "image1d_array_t": true,
+	"image2d_t": true, "image2d_array_t": true, "image2d_depth_t": true, "image2d_array_depth_t": true,
+	"image3d_t": true,
+}
+
+// Go type of a cl element type (scalar or vector), or "" if there is none.
+func elementType(clType string) string {
+	if t, ok := scalarTypes[clType]; ok {
+		return t
+	}
+	for _, n := range []string{"16", "8", "4", "3", "2"} {
+		if strings.HasSuffix(clType, n) {
+			if prefix, ok := vectorPrefixes[strings.TrimSuffix(clType, n)]; ok {
+				return "cl." + prefix + n
+			}
+		}
+	}
+	return ""
+}
+
+// Go type used for a kernel parameter in the generated Launch method.
+func goParamType(p paramDecl) (string, error) {
+	switch {
+	case p.Pointer && p.AddressSpace == "local":
+		return "cl.LocalBuffer", nil
+	case p.Pointer && (p.AddressSpace == "global" || p.AddressSpace == "constant"):
+		if elem := elementType(p.Type); elem != "" {
+			return "*cl.Buffer[" + elem + "]", nil
+		}
+		// Pointers to structs or void: any buffer will do
+		return "*cl.MemObject", nil
+	case p.Pointer:
+		return "", fmt.Errorf("parameter %s: kernel pointer arguments must be global, constant or local", p.Name)
+	case imageTypes[p.Type]:
+		return "*cl.MemObject", nil
+	case p.Type == "sampler_t":
+		return "*cl.Sampler", nil
+	case p.Type == "bool" || p.Type == "size_t" || p.Type == "ptrdiff_t" || p.Type == "intptr_t" || p.Type == "uintptr_t":
+		return "", fmt.Errorf("parameter %s: %s is not allowed as a kernel argument", p.Name, p.Type)
+	}
+	if elem := elementType(p.Type); elem != "" {
+		return elem, nil
+	}
+	// User defined structs and typedefs are passed by value through SetArg
+	return "interface{}", nil
+}
+
+// Converts an OpenCL C identifier such as "vec_add" to an exported Go name.
+func exportedName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	s := b.String()
+	if s == "" || !unicode.IsLetter([]rune(s)[0]) {
+		s = "Kernel" + s
+	}
+	return s
+}
+
+// Reserved names of the generated Launch method.
+var launchNames = map[string]bool{
+	"k": true, "queue": true, "global": true, "local": true, "waitList": true, "err": true, "cl": true,
+}
+
+// Converts a parameter name to a Go identifier that does not clash with Go
+// keywords or the other parameters of Launch.
+func paramName(name string) string {
+	if token.IsKeyword(name) || launchNames[name] {
+		return name + "Arg"
+	}
+	return name
+}
+
+type generator struct {
+	buf bytes.Buffer
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) header(pkg string, files []string) {
+	g.printf("// Code generated by clgen from %s; DO NOT EDIT.\n\n", strings.Join(files, ", "))
+	g.printf("package %s\n\n", pkg)
+	g.printf("import \"github.com/xfong/go2opencl/cl\"\n")
+}
+
+func (g *generator) kernel(file string, k kernelDecl) error {
+	typeName := exportedName(k.Name)
+	types := make([]string, len(k.Params))
+	names := make([]string, len(k.Params))
+	for i, p := range k.Params {
+		t, err := goParamType(p)
+		if err != nil {
+			return fmt.Errorf("%s: kernel %s: %v", file, k.Name, err)
+		}
+		types[i] = t
+		names[i] = paramName(p.Name)
+	}
+
+	g.printf("\n// %s wraps the %s kernel of %s.\n", typeName, k.Name, file)
+	g.printf("type %s struct {\n\tKernel *cl.Kernel\n}\n\n", typeName)
+
+	g.printf("// Creates the %s kernel from program, which must have been built.\n", k.Name)
+	g.printf("func New%s(program *cl.Program) (*%s, error) {\n", typeName, typeName)
+	g.printf("\tkernel, err := program.CreateKernel(%q)\n", k.Name)
+	g.printf("\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	g.printf("\treturn &%s{Kernel: kernel}, nil\n}\n\n", typeName)
+
+	g.printf("func (k *%s) Release() {\n\tk.Kernel.Release()\n}\n\n", typeName)
+
+	g.printf("// Sets the arguments of %s and enqueues it on queue with the given global\n", k.Name)
+	g.printf("// and local work sizes. local may be nil to let the driver choose.\n")
+	g.printf("func (k *%s) Launch(queue *cl.CommandQueue, global, local []int", typeName)
+	for i := range k.Params {
+		g.printf(", %s %s", names[i], types[i])
+	}
+	g.printf(", waitList []*cl.Event) (*cl.Event, error) {\n")
+	for i := range k.Params {
+		g.printf("\tif err := k.Kernel.SetArg(%d, %s); err != nil {\n\t\treturn nil, err\n\t}\n", i, names[i])
+	}
+	g.printf("\treturn queue.EnqueueNDRangeKernel(k.Kernel, nil, global, local, waitList)\n}\n")
+	return nil
+}
+
+// Generates the wrappers for the kernels of each of files, whose sources
+// are given in the same order.
+func generate(pkg string, files, sources []string) ([]byte, error) {
+	g := &generator{}
+	g.header(pkg, files)
+	seen := map[string]string{}
+	for i, src := range sources {
+		kernels, err := parseKernels(src)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", files[i], err)
+		}
+		for _, k := range kernels {
+			typeName := exportedName(k.Name)
+			if prev, dup := seen[typeName]; dup {
+				return nil, fmt.Errorf("%s: kernel %s clashes with a kernel of %s", files[i], k.Name, prev)
+			}
+			seen[typeName] = files[i]
+			if err := g.kernel(files[i], k); err != nil {
+				return nil, err
+			}
+		}
+	}
+	out, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %v", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,70 @@
+// Command clgen generates typed Go wrappers for the kernels of OpenCL C
+// source files, so that kernel arguments are checked by the Go compiler.
+//
+// Usage:
+//
+//	//go:generate go run github.com/xfong/go2opencl/cmd/clgen -o kernels_cl.go vecadd.cl reduce.cl
+//
+// For every __kernel function clgen emits a type named after the kernel
+// (vec_add becomes VecAdd) with a constructor taking a built *cl.Program and
+// a Launch method whose parameters follow the kernel signature:
+//
+//	__global float *    *cl.Buffer[float32]
+//	__local float *     cl.LocalBuffer
+//	float4              cl.Float4
+//	image2d_t           *cl.MemObject
+//	sampler_t           *cl.Sampler
+//	struct / typedef    interface{} (passed by value through Kernel.SetArg)
+//
+// The sources are parsed without a device or compiler. Preprocessor
+// directives are ignored, so kernels declared through macros are not found.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	output := flag.String("o", "", "output file (default: <first input>_cl.go)")
+	pkg := flag.String("pkg", os.Getenv("GOPACKAGE"), "package name of the generated file (default: $GOPACKAGE)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: clgen [-o file] [-pkg name] file.cl...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *pkg == "" {
+		fmt.Fprintln(os.Stderr, "clgen: no package name; use -pkg or run through go generate")
+		os.Exit(2)
+	}
+	files := flag.Args()
+	if *output == "" {
+		*output = strings.TrimSuffix(files[0], filepath.Ext(files[0])) + "_cl.go"
+	}
+
+	sources := make([]string, len(files))
+	for i, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "clgen: %v\n", err)
+			os.Exit(1)
+		}
+		sources[i] = string(data)
+	}
+	out, err := generate(*pkg, files, sources)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "clgen: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*output, out, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "clgen: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// A kernel function found in an OpenCL C source file.
+type kernelDecl struct {
+	Name   string
+	Params []paramDecl
+}
+
+// A parameter of a kernel function.
+type paramDecl struct {
+	Name         string
+	Type         string // base type without qualifiers, e.g. "float4" or "unsigned int"
+	Pointer      bool
+	AddressSpace string // "global", "local", "constant" or "private"
+	Access       string // "read_only", "write_only" or "read_write" for images
+	Const        bool
+}
+
+var addressSpaces = map[string]string{
+	"__global": "global", "global": "global",
+	"__local": "local", "local": "local",
+	"__constant": "constant", "constant": "constant",
+	"__private": "private", "private": "private",
+}
+
+var accessQualifiers = map[string]string{
+	"__read_only": "read_only", "read_only": "read_only",
+	"__write_only": "write_only", "write_only": "write_only",
+	"__read_write": "read_write", "read_write": "read_write",
+}
+
+var ignoredQualifiers = map[string]bool{
+	"restrict": true, "__restrict": true, "volatile": true, "struct": true, "union": true, "enum": true,
+}
+
+// Removes comments and preprocessor directives, keeping line breaks so
+// that positions in errors stay meaningful.
+func stripSource(src string) string {
+	var b strings.Builder
+	lineStart := true
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '/' && i+1 < len(src) && src[i+1] == '/':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			i--
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			i += 2
+			for i+1 < len(src) && !(src[i] == '*' && src[i+1] == '/') {
+				if src[i] == '\n' {
+					b.WriteByte('\n')
+				}
+				i++
+			}
+			i++
+			b.WriteByte(' ')
+		case c == '#' && lineStart:
+			// skip the directive, following backslash continuations
+			for i < len(src) && src[i] != '\n' {
+				if src[i] == '\\' && i+1 < len(src) && src[i+1] == '\n' {
+					b.WriteByte('\n')
+					i++
+				}
+				i++
+			}
+			i--
+		default:
+			b.WriteByte(c)
+			if c == '\n' {
+				lineStart = true
+			} else if c != ' ' && c != '\t' && c != '\r' {
+				lineStart = false
+			}
+		}
+	}
+	return b.String()
+}
+
+// Splits stripped OpenCL C source into identifiers, numbers and single
+// punctuation characters.
+func tokenize(src string) []string {
+	var tokens []string
+	runes := []rune(src)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			j := i
+			for j < len(runes) && (runes[j] == '_' || unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) {
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j
+		case r == '"' || r == '\'':
+			j := i + 1
+			for j < len(runes) && runes[j] != r {
+				if runes[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(runes) {
+				j = len(runes) - 1
+			}
+			tokens = append(tokens, string(runes[i:j+1]))
+			i = j + 1
+		default:
+			tokens = append(tokens, string(r))
+			i++
+		}
+	}
+	return tokens
+}
+
+// Index of the token closing the parenthesis opened at tokens[open].
+func matchParen(tokens []string, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Finds the kernel functions declared or defined in src.
+func parseKernels(src string) ([]kernelDecl, error) {
+	tokens := tokenize(stripSource(src))
+	var kernels []kernelDecl
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i] != "__kernel" && tokens[i] != "kernel" {
+			continue
+		}
+		// Skip attributes and the return type up to the parameter list
+		j := i + 1
+		sawVoid := false
+		name := ""
+		for ; j < len(tokens) && tokens[j] != "("; j++ {
+			switch tokens[j] {
+			case "__attribute__":
+				end := matchParen(tokens, j+1)
+				if end < 0 {
+					return nil, fmt.Errorf("unterminated __attribute__ after kernel")
+				}
+				j = end
+			case "void":
+				sawVoid = true
+			case ";", "{", "}", ")":
+				return nil, fmt.Errorf("malformed kernel declaration near %q", tokens[j])
+			default:
+				name = tokens[j]
+			}
+		}
+		if j == len(tokens) || name == "" {
+			return nil, fmt.Errorf("kernel without a parameter list")
+		}
+		if !sawVoid {
+			return nil, fmt.Errorf("kernel %s must return void", name)
+		}
+		end := matchParen(tokens, j)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated parameter list of kernel %s", name)
+		}
+		params, err := parseParams(tokens[j+1 : end])
+		if err != nil {
+			return nil, fmt.Errorf("kernel %s: %v", name, err)
+		}
+		kernels = append(kernels, kernelDecl{Name: name, Params: params})
+		i = end
+	}
+	return dedupKernels(kernels), nil
+}
+
+// Keeps one entry per kernel when it is both declared and defined.
+func dedupKernels(kernels []kernelDecl) []kernelDecl {
+	seen := map[string]bool{}
+	out := kernels[:0]
+	for _, k := range kernels {
+		if !seen[k.Name] {
+			seen[k.Name] = true
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+func parseParams(tokens []string) ([]paramDecl, error) {
+	if len(tokens) == 0 || len(tokens) == 1 && tokens[0] == "void" {
+		return nil, nil
+	}
+	var params []paramDecl
+	depth := 0
+	start := 0
+	for i := 0; i <= len(tokens); i++ {
+		if i < len(tokens) {
+			switch tokens[i] {
+			case "(":
+				depth++
+				continue
+			case ")":
+				depth--
+				continue
+			case ",":
+				if depth > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+		p, err := parseParam(tokens[start:i], len(params))
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, p)
+		start = i + 1
+	}
+	return params, nil
+}
+
+func parseParam(tokens []string, index int) (paramDecl, error) {
+	var p paramDecl
+	var words []string
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t == "__attribute__":
+			end := matchParen(tokens, i+1)
+			if end < 0 {
+				return p, fmt.Errorf("unterminated __attribute__ in parameter %d", index)
+			}
+			i = end
+		case addressSpaces[t] != "":
+			p.AddressSpace = addressSpaces[t]
+		case accessQualifiers[t] != "":
+			p.Access = accessQualifiers[t]
+		case t == "const" || t == "__const":
+			p.Const = true
+		case ignoredQualifiers[t]:
+		case t == "*":
+			if p.Pointer {
+				return p, fmt.Errorf("parameter %d: pointers to pointers are not allowed in kernels", index)
+			}
+			p.Pointer = true
+		case t == "[":
+			return p, fmt.Errorf("parameter %d: array parameters are not supported", index)
+		default:
+			words = append(words, t)
+		}
+	}
+	if len(words) < 2 {
+		return p, fmt.Errorf("parameter %d has no name", index)
+	}
+	p.Name = words[len(words)-1]
+	p.Type = strings.Join(words[:len(words)-1], " ")
+	if p.AddressSpace == "" {
+		p.AddressSpace = "private"
+	}
+	return p, nil
+}
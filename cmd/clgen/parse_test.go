@@ -0,0 +1,120 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+const testSource = `
+#define N 16
+// kernel void commented_out(int x) {}
+typedef struct { float a; int b; } params_t;
+
+__kernel __attribute__((reqd_work_group_size(16, 1, 1)))
+void vec_add(__global const float *a, __global const float * restrict b,
+             __global float *out, const unsigned int n)
+{
+	int i = get_global_id(0);
+	if (i < n) out[i] = a[i] + b[i];
+}
+
+kernel void blur(read_only image2d_t src, write_only image2d_t dst, sampler_t smp,
+                 __local float4 *tile, params_t p, float4 scale, int range) {}
+`
+
+func TestParseKernels(t *testing.T) {
+	kernels, err := parseKernels(testSource)
+	if err != nil {
+		t.Fatalf("parseKernels failed: %v", err)
+	}
+	if len(kernels) != 2 || kernels[0].Name != "vec_add" || kernels[1].Name != "blur" {
+		t.Fatalf("unexpected kernels: %+v", kernels)
+	}
+	b := kernels[0].Params[1]
+	if b.Name != "b" || b.Type != "float" || !b.Pointer || !b.Const || b.AddressSpace != "global" {
+		t.Errorf("unexpected parameter: %+v", b)
+	}
+	if n := kernels[0].Params[3]; n.Type != "unsigned int" || n.Pointer || n.AddressSpace != "private" {
+		t.Errorf("unexpected parameter: %+v", n)
+	}
+	if src := kernels[1].Params[0]; src.Type != "image2d_t" || src.Access != "read_only" {
+		t.Errorf("unexpected image parameter: %+v", src)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	out, err := generate("kernels", []string{"test.cl"}, []string{testSource})
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	typeCheck(t, out)
+	code := string(out)
+	for _, want := range []string{
+		"func NewVecAdd(program *cl.Program) (*VecAdd, error)",
+		"a *cl.Buffer[float32], b *cl.Buffer[float32], out *cl.Buffer[float32], n uint32",
+		"src *cl.MemObject, dst *cl.MemObject, smp *cl.Sampler, tile cl.LocalBuffer, p interface{}, scale cl.Float4, rangeArg int32",
+		"k.Kernel.SetArg(6, rangeArg)",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code lacks %q:\n%s", want, code)
+		}
+	}
+
+	if _, err := generate("kernels", []string{"bad.cl"}, []string{"__kernel void f(size_t n) {}"}); err == nil {
+		t.Errorf("expected an error for a size_t argument")
+	}
+}
+
+// Stands in for package cl when type checking generated code, which cannot
+// import the real package without cgo.
+const clStub = `package cl
+
+type Program struct{}
+type Kernel struct{}
+type CommandQueue struct{}
+type Event struct{}
+type MemObject struct{}
+type Sampler struct{}
+type LocalBuffer int
+type Float4 [4]float32
+type Buffer[T any] struct{ *MemObject }
+
+func (p *Program) CreateKernel(name string) (*Kernel, error) { return nil, nil }
+func (k *Kernel) Release()                                  {}
+func (k *Kernel) SetArg(index int, arg interface{}) error   { return nil }
+func (q *CommandQueue) EnqueueNDRangeKernel(kernel *Kernel, globalWorkOffset, globalWorkSize, localWorkSize []int, eventWaitList []*Event) (*Event, error) {
+	return nil, nil
+}
+`
+
+type stubImporter map[string]*types.Package
+
+func (s stubImporter) Import(path string) (*types.Package, error) {
+	return s[path], nil
+}
+
+// Parses and type checks generated code against clStub.
+func typeCheck(t *testing.T, code []byte) {
+	t.Helper()
+	fset := token.NewFileSet()
+	stub, err := parser.ParseFile(fset, "cl.go", clStub, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clPkg, err := new(types.Config).Check("github.com/xfong/go2opencl/cl", fset, []*ast.File{stub}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := parser.ParseFile(fset, "out.go", code, 0)
+	if err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, code)
+	}
+	conf := types.Config{Importer: stubImporter{"github.com/xfong/go2opencl/cl": clPkg}}
+	if _, err := conf.Check("kernels", fset, []*ast.File{file}, nil); err != nil {
+		t.Fatalf("generated code does not compile: %v\n%s", err, code)
+	}
+}